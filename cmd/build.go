@@ -1,18 +1,38 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"vess/internal/cache"
+	"vess/internal/config"
 	"vess/internal/docker"
+	"vess/internal/generator"
 	"vess/internal/logger"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	dockerfile string
-	tag        string
-	noCache    bool
+	dockerfile   string
+	tag          string
+	noCache      bool
+	buildTimeout time.Duration
+	progressMode string
+	metadataFile string
+	verifyBuild  bool
+	buildEnvFile string
+	pushBuild    bool
+	platforms    []string
+	ociOutput    string
+	buildTarget  string
+	contextDir   string
 )
 
 var buildCmd = &cobra.Command{
@@ -22,9 +42,58 @@ var buildCmd = &cobra.Command{
 	
 This command uses the Docker SDK to build the image and streams
 the build output to the terminal. You can specify a custom tag
-and control caching behavior.`,
+and control caching behavior.
+
+Pressing Ctrl-C (or sending SIGTERM) cancels the build on the daemon.
+Use --timeout to abort builds that take too long.
+
+Build progress is rendered according to --progress: "tty" redraws pull
+progress in place, "plain" prints one line per event, and "json" writes
+one JSON event per line to stdout for CI (log messages go to stderr).
+The default "auto" picks tty for terminals and plain otherwise.
+
+After a successful build the image is inspected and its ID, digests,
+size, layers, creation time and labels are reported. Use --metadata-file
+to also write this report as JSON.
+
+With --verify, the image is checked against --env-file after the build
+(see "vess verify"). With --push, the image is pushed to its registry
+once the build (and verification, if requested) succeeded.
+
+With more than one --platform, the image is built for every platform and
+assembled into an OCI image index using docker buildx (or podman build
+--manifest on Podman). Such images cannot be loaded into the local image
+store, so combine --platform with --push or --oci-output. Platforms are
+validated against the platforms published for the base images.
+
+Cached extensions staged in .vess/ext by "vess generate" are only valid
+for the architecture they were built for. Builds for another platform, or
+for more than one, are refused; generate the Dockerfile with --arch or
+--no-ext-cache first.
+
+Dockerfiles that need BuildKit (a "# syntax=" directive or RUN --mount,
+as written by "vess generate --mode optimized") are built with docker
+buildx and loaded into the local image store, or with podman build on
+Podman engines.
+
+Use --target to build the "dev" or "prod" target of a Dockerfile generated
+from an env file with PHP_DEV_EXTENSIONS or PHP_DEV_INI. Without --target
+the last stage, "prod", is built. --verify checks the extensions of the
+selected target.
+
+By default only the Dockerfile and the .vess directory next to it are sent
+to the builder. Use --context to also send a directory, e.g. for files
+copied by HOOK_* instructions.`,
 	Example: `  vess build --dockerfile Dockerfile --tag my-php:8.2
-  vess build -d Dockerfile.alpine -t my-app:latest --no-cache`,
+  vess build -d Dockerfile.alpine -t my-app:latest --no-cache
+  vess build -d Dockerfile -t my-app:latest --timeout 15m
+  vess build -d Dockerfile -t my-app:latest --progress json > build-events.jsonl
+  vess build -d Dockerfile -t my-app:latest --metadata-file image.json
+  vess build -d Dockerfile -t my-app:latest --verify -e app.env -p 8.3
+  vess build -d Dockerfile -t localhost:5000/my-app:latest --push
+  vess build -d Dockerfile -t my-app:dev --target dev --verify -e examples/development.env
+  vess build -d Dockerfile -t my-app:latest --context .
+  vess build -d Dockerfile -t registry.example.com/my-app:latest --platform linux/amd64,linux/arm64 --push`,
 	RunE: runBuild,
 }
 
@@ -34,31 +103,248 @@ func init() {
 	buildCmd.Flags().StringVarP(&dockerfile, "dockerfile", "d", "Dockerfile", "Path to Dockerfile")
 	buildCmd.Flags().StringVarP(&tag, "tag", "t", "", "Image tag (e.g., my-php:8.2)")
 	buildCmd.Flags().BoolVar(&noCache, "no-cache", false, "Do not use cache when building")
+	buildCmd.Flags().DurationVar(&buildTimeout, "timeout", 0, "Abort the build after this duration (e.g., 10m, 1h30m; 0 disables)")
+	buildCmd.Flags().StringVar(&progressMode, "progress", docker.ProgressAuto, "Progress output (auto, plain, tty, json)")
+	buildCmd.Flags().StringVar(&metadataFile, "metadata-file", "", "Write built image metadata as JSON to this file")
+	buildCmd.Flags().BoolVar(&verifyBuild, "verify", false, "Verify loaded extensions after the build")
+	buildCmd.Flags().StringVarP(&buildEnvFile, "env-file", "e", ".env", "Env file used by --verify")
+	buildCmd.Flags().BoolVar(&pushBuild, "push", false, "Push the image to its registry after the build")
+	buildCmd.Flags().StringSliceVar(&platforms, "platform", nil, "Target platforms (e.g., linux/amd64,linux/arm64)")
+	buildCmd.Flags().StringVar(&ociOutput, "oci-output", "", "Export a multi-platform build as an OCI archive to this path")
+	buildCmd.Flags().StringVar(&buildTarget, "target", "", "Build this target stage (dev, prod)")
+	buildCmd.Flags().StringVar(&contextDir, "context", "", "Directory sent as build context for COPY/ADD instructions")
 	buildCmd.MarkFlagRequired("tag")
 }
 
 func runBuild(cmd *cobra.Command, args []string) error {
 	log := logger.New(IsVerbose())
-	
+
+	renderer, err := docker.NewProgressRenderer(progressMode, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if progressMode == docker.ProgressJSON {
+		// Keep stdout a clean event stream
+		log.SetOutput(os.Stderr)
+	}
+
 	log.Info("Starting Docker image build")
-	log.Debug("Dockerfile: %s, Tag: %s, NoCache: %v", dockerfile, tag, noCache)
+	log.Debug("Dockerfile: %s, Tag: %s, Target: %s, NoCache: %v, Timeout: %s", dockerfile, tag, buildTarget, noCache, buildTimeout)
+
+	ctx := cmd.Context()
+	if buildTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, buildTimeout)
+		defer cancel()
+	}
+
+	// Validate platforms
+	buildPlatforms, err := validateBuildPlatforms(log)
+	if err != nil {
+		return err
+	}
+	multiPlatform := len(buildPlatforms) > 1
+	if err := checkCachedArch(buildPlatforms, ""); err != nil {
+		return err
+	}
 
 	// Create Docker client
-	client, err := docker.NewClient()
+	client, err := connectDocker(ctx, log)
 	if err != nil {
-		return fmt.Errorf("failed to create Docker client: %w", err)
+		return err
 	}
 	defer client.Close()
 
+	if engine, err := client.Engine(ctx); err == nil {
+		log.Info("Using %s", engine)
+		if len(buildPlatforms) == 0 {
+			// Without --platform the image is built for the engine's architecture
+			if err := checkCachedArch(nil, engine.Arch); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Build image
 	log.Info("Building image: %s", tag)
 	builder := docker.NewBuilder(client, log)
-	if err := builder.Build(dockerfile, tag, noCache); err != nil {
+	result, err := builder.Build(ctx, docker.BuildOptions{
+		Dockerfile: dockerfile,
+		Tag:        tag,
+		NoCache:    noCache,
+		Labels: map[string]string{
+			generator.LabelManaged:    "true",
+			generator.LabelDockerfile: dockerfile,
+		},
+		Target:     buildTarget,
+		ContextDir: contextDir,
+		Platforms:  buildPlatforms,
+		Push:       pushBuild && (multiPlatform || ociOutput != ""),
+		OCIOutput:  ociOutput,
+		Progress:   renderer,
+	})
+	if err != nil {
 		return fmt.Errorf("failed to build image: %w", err)
 	}
 
 	log.Success("Image built successfully: %s", tag)
-	log.Info("Run: docker run --rm %s php -m", tag)
-	
+	if multiPlatform || ociOutput != "" {
+		return reportMultiPlatformBuild(log, result)
+	}
+	log.Info("Built in %s (%d steps)", result.Duration.Round(time.Second), len(result.Steps))
+
+	// Report image metadata
+	meta, err := builder.InspectImage(ctx, tag)
+	if err != nil {
+		return err
+	}
+	printImageMetadata(log, meta)
+
+	if metadataFile != "" {
+		data, err := json.MarshalIndent(meta, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal image metadata: %w", err)
+		}
+		if err := os.WriteFile(metadataFile, data, 0644); err != nil {
+			return fmt.Errorf("failed to write metadata file: %w", err)
+		}
+		log.Success("Image metadata written: %s", metadataFile)
+	}
+
+	if verifyBuild {
+		if err := verifyImage(ctx, log, client, tag, buildEnvFile, buildTarget); err != nil {
+			return err
+		}
+	} else {
+		log.Info("Run: vess verify --image %s --env-file <env-file>", tag)
+	}
+
+	if pushBuild {
+		pushRenderer, err := docker.NewProgressRenderer(progressMode, os.Stdout)
+		if err != nil {
+			return err
+		}
+		if _, err := pushImage(ctx, log, builder, tag, pushRenderer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateBuildPlatforms normalizes --platform and checks it against the base
+// images
+func validateBuildPlatforms(log *logger.Logger) ([]string, error) {
+	if len(platforms) == 0 {
+		if ociOutput != "" {
+			return nil, fmt.Errorf("--oci-output requires --platform")
+		}
+		return nil, nil
+	}
+
+	normalized, err := config.NewValidator().ValidatePlatforms(GetOSType(), platforms)
+	if err != nil {
+		return nil, fmt.Errorf("platform validation failed: %w", err)
+	}
+	if len(normalized) > 1 && !pushBuild && ociOutput == "" {
+		return nil, fmt.Errorf("multi-platform builds must be pushed (--push) or exported (--oci-output)")
+	}
+	if verifyBuild && (len(normalized) > 1 || ociOutput != "") {
+		return nil, fmt.Errorf("--verify is not supported for multi-platform or OCI archive builds; verify each platform after pulling it")
+	}
+
+	log.Debug("Platforms: %s", strings.Join(normalized, ", "))
+	return normalized, nil
+}
+
+// checkCachedArch refuses to build for another architecture than the one the
+// cached extensions staged next to the Dockerfile were built for
+func checkCachedArch(buildPlatforms []string, engineArch string) error {
+	dir := filepath.Join(filepath.Dir(dockerfile), generator.CacheContextDir)
+	arch, err := cache.ExportedArch(dir)
+	if err != nil {
+		return err
+	}
+	if arch == "" {
+		return nil
+	}
+
+	if len(buildPlatforms) > 1 {
+		return fmt.Errorf("%s holds cached %s extensions, which cannot be used for a multi-platform build; generate the Dockerfile with --no-ext-cache", dir, arch)
+	}
+	target := engineArch
+	if len(buildPlatforms) == 1 {
+		target = strings.Split(buildPlatforms[0], "/")[1]
+	}
+	if target != "" && target != arch {
+		return fmt.Errorf("%s holds cached %s extensions but the build targets %s; generate the Dockerfile with --arch %s or --no-ext-cache", dir, arch, target, target)
+	}
+	return nil
+}
+
+// reportMultiPlatformBuild logs the result of an image index build
+func reportMultiPlatformBuild(log *logger.Logger, result *docker.BuildResult) error {
+	log.Info("Platforms: %s", strings.Join(result.Platforms, ", "))
+	log.Info("Built in %s", result.Duration.Round(time.Second))
+	if result.Digest != "" {
+		log.Info("Index digest: %s", result.Digest)
+	}
+	if pushBuild {
+		log.Success("Pushed %s", tag)
+	}
+	if ociOutput != "" {
+		log.Success("OCI archive written: %s", ociOutput)
+	}
+
+	if metadataFile != "" {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal build metadata: %w", err)
+		}
+		if err := os.WriteFile(metadataFile, data, 0644); err != nil {
+			return fmt.Errorf("failed to write metadata file: %w", err)
+		}
+		log.Success("Build metadata written: %s", metadataFile)
+	}
+
 	return nil
 }
+
+// printImageMetadata logs a human-readable image report
+func printImageMetadata(log *logger.Logger, meta *docker.ImageMetadata) {
+	log.Info("Image ID: %s", meta.ID)
+	for _, digest := range meta.RepoDigests {
+		log.Info("Digest: %s", digest)
+	}
+	log.Info("Size: %s (%s/%s)", docker.FormatSize(meta.Size), meta.OS, meta.Architecture)
+	if !meta.Created.IsZero() {
+		log.Info("Created: %s", meta.Created.Local().Format(time.RFC3339))
+	}
+
+	log.Info("Layers: %d", meta.LayerCount)
+	for _, layer := range meta.Layers {
+		log.Info("  %9s  %s", docker.FormatSize(layer.Size), truncate(layer.CreatedBy, 80))
+	}
+
+	if len(meta.Labels) > 0 {
+		keys := make([]string, 0, len(meta.Labels))
+		for key := range meta.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		log.Info("Labels:")
+		for _, key := range keys {
+			log.Info("  %s=%s", key, meta.Labels[key])
+		}
+	}
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	runes := []rune(strings.Join(strings.Fields(s), " "))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	return string(runes[:n-3]) + "..."
+}
@@ -0,0 +1,544 @@
+package cmd
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"vess/internal/cache"
+	"vess/internal/config"
+	"vess/internal/docker"
+	"vess/internal/extensions"
+	"vess/internal/generator"
+	"vess/internal/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cacheDir        string
+	cacheEnvFile    string
+	cacheImageType  string
+	cachePlatform   string
+	cacheFormat     string
+	cacheExtensions []string
+	cacheRepo       string
+	cacheArch       string
+)
+
+// cacheConfContainerDir is where official php images keep extension ini files
+const cacheConfContainerDir = "/usr/local/etc/php/conf.d"
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the pre-built extension cache",
+	Long: `Manage the cache of pre-built PHP extensions.
+
+Compiling extensions such as imagick from PECL takes minutes. The cache
+stores the compiled .so file and ini snippet of each extension, keyed by
+extension, extension version, PHP version, OS and architecture
+(e.g. imagick-3.7.0-php8.3-alpine-amd64).
+
+"vess generate" copies cached extensions into the image instead of
+compiling them, and compiles everything that is not cached. Pin PECL
+versions with PECL_VERSIONS=imagick:3.7.0,redis:6.0.2 in the env file to
+select exact cache entries; without a pin the newest cached version is used.
+
+The cache lives in --cache-dir (default: $VESS_CACHE_DIR or the user cache
+directory). "vess cache push" and "vess cache pull" share it through an
+OCI registry, storing each entry as a small FROM scratch image.`,
+}
+
+var cachePopulateCmd = &cobra.Command{
+	Use:   "populate",
+	Short: "Build the extensions of an env file and store them in the cache",
+	Long: `Build the extensions listed in --env-file for the selected OS and PHP
+version, then copy the compiled modules and ini snippets into the cache.
+Existing entries with the same key are replaced.`,
+	Example: `  vess cache populate -e app.env -o alpine -p 8.3
+  vess cache populate -e app.env -o ubuntu -p 8.2 --platform linux/arm64`,
+	RunE: runCachePopulate,
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List cached extensions",
+	Example: `  vess cache list
+  vess cache list --format json`,
+	RunE: runCacheList,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove cached extensions",
+	Long:  `Remove all cached extensions, or only those named with --extension.`,
+	Example: `  vess cache clear
+  vess cache clear --extension imagick,redis`,
+	RunE: runCacheClear,
+}
+
+var cachePushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Push cached extensions to an OCI registry",
+	Long: `Push every cached extension (or those named with --extension) to --repo.
+Each entry is tagged with its key, and the newest version of each extension
+is also tagged with "latest" in place of the version.`,
+	Example: `  vess cache push --repo registry.example.com/vess-cache`,
+	RunE:    runCachePush,
+}
+
+var cachePullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Pull cached extensions from an OCI registry",
+	Long: `Pull the extensions listed in --env-file for the selected OS, PHP version
+and --arch from --repo into the local cache. Pinned PECL_VERSIONS are pulled
+by version, other extensions by their "latest" tag. Missing entries are
+reported and skipped; they will be compiled during the build.`,
+	Example: `  vess cache pull --repo registry.example.com/vess-cache -e app.env -o alpine -p 8.3`,
+	RunE:    runCachePull,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cachePopulateCmd, cacheListCmd, cacheClearCmd, cachePushCmd, cachePullCmd)
+
+	cacheCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", cache.DefaultDir(), "Extension cache directory")
+
+	cachePopulateCmd.Flags().StringVarP(&cacheEnvFile, "env-file", "e", ".env", "Path to env file containing PHP extensions")
+	cachePopulateCmd.Flags().StringVarP(&cacheImageType, "type", "t", "cli", "PHP base image type used for the build")
+	cachePopulateCmd.Flags().StringVar(&cachePlatform, "platform", "", "Build for this platform (e.g., linux/arm64)")
+	cachePopulateCmd.MarkFlagRequired("env-file")
+
+	cacheListCmd.Flags().StringVar(&cacheFormat, "format", "table", "Output format (table, json)")
+
+	cacheClearCmd.Flags().StringSliceVar(&cacheExtensions, "extension", nil, "Only remove these extensions")
+
+	cachePushCmd.Flags().StringVar(&cacheRepo, "repo", "", "Registry repository (e.g., registry.example.com/vess-cache)")
+	cachePushCmd.Flags().StringSliceVar(&cacheExtensions, "extension", nil, "Only push these extensions")
+	cachePushCmd.MarkFlagRequired("repo")
+
+	cachePullCmd.Flags().StringVar(&cacheRepo, "repo", "", "Registry repository (e.g., registry.example.com/vess-cache)")
+	cachePullCmd.Flags().StringVarP(&cacheEnvFile, "env-file", "e", ".env", "Path to env file containing PHP extensions")
+	cachePullCmd.Flags().StringVar(&cacheArch, "arch", runtime.GOARCH, "Architecture to pull (e.g., amd64, arm64)")
+	cachePullCmd.MarkFlagRequired("repo")
+	cachePullCmd.MarkFlagRequired("env-file")
+}
+
+func runCachePopulate(cmd *cobra.Command, args []string) error {
+	log := logger.New(IsVerbose())
+	ctx := cmd.Context()
+
+	cfg, err := config.ParseEnvFile(cacheEnvFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse env file: %w", err)
+	}
+	validator := config.NewValidator()
+	if err := validator.Validate(cfg, GetOSType(), GetPHPVersion(), cacheImageType); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	if typ, _ := extensions.GetImageType(cacheImageType); typ.ZTS {
+		return fmt.Errorf("%s images use a thread-safe PHP build and cannot populate the extension cache", cacheImageType)
+	}
+	var buildPlatforms []string
+	if cachePlatform != "" {
+		if buildPlatforms, err = validator.ValidatePlatforms(GetOSType(), []string{cachePlatform}); err != nil {
+			return fmt.Errorf("platform validation failed: %w", err)
+		}
+	}
+
+	// Generate a Dockerfile that compiles every extension
+	content, err := generator.New(GetOSType(), GetPHPVersion(), cacheImageType).Generate(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to generate Dockerfile: %w", err)
+	}
+	workDir, err := os.MkdirTemp("", "vess-cache-")
+	if err != nil {
+		return fmt.Errorf("failed to create build directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+	dockerfilePath := filepath.Join(workDir, "Dockerfile")
+	if err := os.WriteFile(dockerfilePath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write Dockerfile: %w", err)
+	}
+
+	client, err := connectDocker(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	builder := docker.NewBuilder(client, log)
+
+	renderer, err := cacheProgressRenderer()
+	if err != nil {
+		return err
+	}
+	buildTag := fmt.Sprintf("vess-cache-build:%s-%d", GetOSType(), time.Now().Unix())
+	log.Info("Building %s for PHP %s on %s...", strings.Join(cfg.Extensions, ", "), GetPHPVersion(), GetOSType())
+	if _, err := builder.Build(ctx, docker.BuildOptions{
+		Dockerfile: dockerfilePath,
+		Tag:        buildTag,
+		Platforms:  buildPlatforms,
+		Progress:   renderer,
+	}); err != nil {
+		return fmt.Errorf("failed to build extensions: %w", err)
+	}
+	defer func() {
+		if err := builder.RemoveImage(context.WithoutCancel(ctx), buildTag, true); err != nil {
+			log.Warn("Failed to remove %s: %v", buildTag, err)
+		}
+	}()
+
+	meta, err := builder.InspectImage(ctx, buildTag)
+	if err != nil {
+		return err
+	}
+
+	// Ask PHP where the modules are and which versions were built
+	modules := make([]string, 0, len(cfg.Extensions))
+	for _, extName := range cfg.Extensions {
+		modules = append(modules, extensions.GetModuleName(extName))
+	}
+	script := `echo ini_get("extension_dir"), "\n"; foreach (array_slice($argv, 1) as $m) { echo $m, "=", phpversion($m), "\n"; }`
+	result, err := client.RunCommand(ctx, buildTag, append([]string{"php", "-r", script, "--"}, modules...))
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to query PHP in %s: %s", buildTag, strings.TrimSpace(result.Stderr))
+	}
+	lines := strings.Split(strings.TrimSpace(result.Stdout), "\n")
+	extensionDir := strings.TrimSpace(lines[0])
+	versions := make(map[string]string)
+	for _, line := range lines[1:] {
+		if module, version, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			versions[module] = version
+		}
+	}
+
+	moduleFiles, err := readImageFiles(ctx, client, buildTag, extensionDir)
+	if err != nil {
+		return err
+	}
+	iniFiles, err := readImageFiles(ctx, client, buildTag, cacheConfContainerDir)
+	if err != nil {
+		return err
+	}
+
+	store := cache.New(cacheDir)
+	added := 0
+	for _, extName := range cfg.Extensions {
+		entry := &cache.Entry{
+			Extension:    extName,
+			Version:      versions[extensions.GetModuleName(extName)],
+			PHPVersion:   GetPHPVersion(),
+			OSType:       GetOSType(),
+			Arch:         meta.Architecture,
+			ExtensionDir: extensionDir,
+			ModuleFile:   extName + ".so",
+		}
+		if entry.Version == "" {
+			entry.Version = cfg.PECLVersions[extName]
+		}
+		if entry.Version == "" {
+			log.Warn("Skipping %s: PHP does not report its version", extName)
+			continue
+		}
+
+		module, ok := moduleFiles[entry.ModuleFile]
+		if !ok {
+			log.Warn("Skipping %s: %s not found in %s", extName, entry.ModuleFile, extensionDir)
+			continue
+		}
+		ini, ok := iniFiles["docker-php-ext-"+extName+".ini"]
+		if ok {
+			entry.IniFile = "docker-php-ext-" + extName + ".ini"
+		}
+
+		if err := store.Add(entry, module, ini); err != nil {
+			return err
+		}
+		log.Success("Cached %s", entry.Key())
+		added++
+	}
+
+	log.Info("%d extension(s) cached in %s", added, store.Dir())
+	return nil
+}
+
+func runCacheList(cmd *cobra.Command, args []string) error {
+	if cacheFormat != "table" && cacheFormat != "json" {
+		return fmt.Errorf("unsupported format: %s (must be 'table' or 'json')", cacheFormat)
+	}
+
+	entries, err := cache.New(cacheDir).List()
+	if err != nil {
+		return err
+	}
+
+	if cacheFormat == "json" {
+		if entries == nil {
+			entries = []*cache.Entry{}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal cache entries: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EXTENSION\tVERSION\tPHP\tOS\tARCH\tSIZE\tCREATED")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Extension, entry.Version, entry.PHPVersion, entry.OSType, entry.Arch,
+			docker.FormatSize(entry.Size), entry.Created.Local().Format("2006-01-02 15:04"))
+	}
+	return w.Flush()
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	log := logger.New(IsVerbose())
+
+	store := cache.New(cacheDir)
+	entries, err := selectCacheEntries(store)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := store.Remove(entry); err != nil {
+			return err
+		}
+		log.Debug("Removed %s", entry.Key())
+	}
+	log.Success("Removed %d cached extension(s)", len(entries))
+	return nil
+}
+
+func runCachePush(cmd *cobra.Command, args []string) error {
+	log := logger.New(IsVerbose())
+	ctx := cmd.Context()
+
+	store := cache.New(cacheDir)
+	entries, err := selectCacheEntries(store)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		log.Info("Nothing to push")
+		return nil
+	}
+
+	client, err := connectDocker(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	builder := docker.NewBuilder(client, log)
+
+	for _, entry := range entries {
+		if err := pushCacheEntry(ctx, log, builder, store, entry); err != nil {
+			return err
+		}
+	}
+
+	log.Success("Pushed %d cached extension(s) to %s", len(entries), cacheRepo)
+	return nil
+}
+
+// pushCacheEntry packs entry into a scratch image and pushes it under its key
+// and, when it is the newest version, under its "latest" key
+func pushCacheEntry(ctx context.Context, log *logger.Logger, builder *docker.Builder, store *cache.Cache, entry *cache.Entry) error {
+	workDir, err := os.MkdirTemp("", "vess-cache-")
+	if err != nil {
+		return fmt.Errorf("failed to create build directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	if err := entry.CopyTo(filepath.Join(workDir, docker.VessDir)); err != nil {
+		return err
+	}
+	dockerfilePath := filepath.Join(workDir, "Dockerfile")
+	if err := os.WriteFile(dockerfilePath, []byte("FROM scratch\nCOPY .vess/ /vess/\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write Dockerfile: %w", err)
+	}
+
+	renderer, err := cacheProgressRenderer()
+	if err != nil {
+		return err
+	}
+	ref := cacheRepo + ":" + entry.Key()
+	if _, err := builder.Build(ctx, docker.BuildOptions{Dockerfile: dockerfilePath, Tag: ref, Progress: renderer}); err != nil {
+		return fmt.Errorf("failed to package %s: %w", entry.Key(), err)
+	}
+	refs := []string{ref}
+
+	newest, err := store.Lookup(entry.Extension, "", entry.PHPVersion, entry.OSType, entry.Arch)
+	if err != nil {
+		return err
+	}
+	if newest != nil && newest.Key() == entry.Key() {
+		latest := cacheRepo + ":" + cache.Key(entry.Extension, "latest", entry.PHPVersion, entry.OSType, entry.Arch)
+		if err := builder.TagImage(ctx, ref, latest); err != nil {
+			return err
+		}
+		refs = append(refs, latest)
+	}
+
+	for _, r := range refs {
+		renderer, err := cacheProgressRenderer()
+		if err != nil {
+			return err
+		}
+		if _, err := builder.Push(ctx, r, renderer); err != nil {
+			return err
+		}
+		log.Success("Pushed %s", r)
+		if err := builder.RemoveImage(ctx, r, false); err != nil {
+			log.Warn("Failed to remove %s: %v", r, err)
+		}
+	}
+	return nil
+}
+
+func runCachePull(cmd *cobra.Command, args []string) error {
+	log := logger.New(IsVerbose())
+	ctx := cmd.Context()
+
+	cfg, err := config.ParseEnvFile(cacheEnvFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse env file: %w", err)
+	}
+
+	client, err := connectDocker(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	builder := docker.NewBuilder(client, log)
+
+	store := cache.New(cacheDir)
+	pulled := 0
+	for _, extName := range cfg.Extensions {
+		version := cfg.PECLVersions[extName]
+		if version == "" {
+			version = "latest"
+		}
+		ref := cacheRepo + ":" + cache.Key(extName, version, GetPHPVersion(), GetOSType(), cacheArch)
+
+		renderer, err := cacheProgressRenderer()
+		if err != nil {
+			return err
+		}
+		if err := builder.Pull(ctx, ref, renderer); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			log.Warn("Skipping %s: %v", extName, err)
+			continue
+		}
+
+		entry, err := importCacheImage(ctx, client, store, ref)
+		if rmErr := builder.RemoveImage(context.WithoutCancel(ctx), ref, false); rmErr != nil {
+			log.Warn("Failed to remove %s: %v", ref, rmErr)
+		}
+		if err != nil {
+			return err
+		}
+		log.Success("Cached %s", entry.Key())
+		pulled++
+	}
+
+	log.Info("%d of %d extension(s) pulled into %s", pulled, len(cfg.Extensions), store.Dir())
+	return nil
+}
+
+// importCacheImage copies the entry packed by pushCacheEntry out of ref into store
+func importCacheImage(ctx context.Context, client *docker.Client, store *cache.Cache, ref string) (*cache.Entry, error) {
+	reader, err := client.CopyFromImage(ctx, ref, "/vess")
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	workDir, err := os.MkdirTemp("", "vess-cache-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	if err := docker.ExtractTar(reader, workDir); err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", ref, err)
+	}
+	return store.Import(filepath.Join(workDir, "vess"))
+}
+
+// selectCacheEntries returns the cached entries, limited to --extension if set
+func selectCacheEntries(store *cache.Cache) ([]*cache.Entry, error) {
+	entries, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(cacheExtensions) == 0 {
+		return entries, nil
+	}
+
+	var selected []*cache.Entry
+	for _, entry := range entries {
+		for _, name := range cacheExtensions {
+			if entry.Extension == name {
+				selected = append(selected, entry)
+				break
+			}
+		}
+	}
+	return selected, nil
+}
+
+// readImageFiles returns the regular files directly inside dir of imageRef by name
+func readImageFiles(ctx context.Context, client *docker.Client, imageRef, dir string) (map[string][]byte, error) {
+	reader, err := client.CopyFromImage(ctx, imageRef, dir)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from %s: %w", dir, imageRef, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from %s: %w", header.Name, imageRef, err)
+		}
+		files[path.Base(header.Name)] = data
+	}
+}
+
+// cacheProgressRenderer shows build and transfer output only with --verbose
+func cacheProgressRenderer() (docker.ProgressRenderer, error) {
+	if IsVerbose() {
+		return docker.NewProgressRenderer(docker.ProgressPlain, os.Stdout)
+	}
+	return docker.NewProgressRenderer(docker.ProgressPlain, io.Discard)
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"vess/internal/config"
+	"vess/internal/diff"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffType       string
+	diffToOS       string
+	diffToPHP      string
+	diffToType     string
+	diffFormat     string
+	diffExitStatus bool
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <env-file> [<other-env-file>]",
+	Short: "Compare two configs, or one config across OS and PHP versions",
+	Long: `Compare what two env files, or one env file rendered for another OS, PHP
+version or image type, install: the base image, extensions, pinned PECL
+versions, build and runtime OS packages, the exposed port, process setup,
+RoadRunner release, nginx server block and health check, and the extension
+conflicts and validation problems introduced or resolved.
+
+The first side uses --os, --php-version and --type. The second side uses the
+second env file, if given, and --to-os, --to-php-version and --to-type,
+which default to the first side's values.`,
+	Example: `  vess diff old.env new.env
+  vess diff app.env -p 7.4 --to-php-version 8.3
+  vess diff app.env --to-os ubuntu --type fpm --to-type apache
+  vess diff old.env new.env --format json`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVarP(&diffType, "type", "t", "fpm", "Image type of the first side")
+	diffCmd.Flags().StringVar(&diffToOS, "to-os", "", "Operating system of the second side (default: --os)")
+	diffCmd.Flags().StringVar(&diffToPHP, "to-php-version", "", "PHP version of the second side (default: --php-version)")
+	diffCmd.Flags().StringVar(&diffToType, "to-type", "", "Image type of the second side (default: --type)")
+	diffCmd.Flags().StringVar(&diffFormat, "format", "text", "Output format (text, json)")
+	diffCmd.Flags().BoolVar(&diffExitStatus, "exit-code", false, "Exit with status 1 when the sides differ")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	if diffFormat != "text" && diffFormat != "json" {
+		return fmt.Errorf("unsupported format: %s (must be 'text' or 'json')", diffFormat)
+	}
+
+	from, err := config.ParseEnvFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse env file: %w", err)
+	}
+	to := from
+	if len(args) == 2 {
+		if to, err = config.ParseEnvFile(args[1]); err != nil {
+			return fmt.Errorf("failed to parse env file: %w", err)
+		}
+	}
+
+	fromTarget := &diff.Target{Config: from, OSType: GetOSType(), PHPVersion: GetPHPVersion(), ImageType: diffType}
+	toTarget := &diff.Target{Config: to, OSType: orDefault(diffToOS, fromTarget.OSType), PHPVersion: orDefault(diffToPHP, fromTarget.PHPVersion), ImageType: orDefault(diffToType, diffType)}
+	if len(args) == 1 && *toTarget == *fromTarget {
+		return fmt.Errorf("nothing to compare: give a second env file or one of --to-os, --to-php-version, --to-type")
+	}
+
+	result := diff.Compare(fromTarget, toTarget)
+	if diffFormat == "json" {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printDiff(os.Stdout, result)
+	}
+
+	if diffExitStatus && !result.Empty() {
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return fmt.Errorf("targets differ")
+	}
+	return nil
+}
+
+// printDiff prints a result as sections of added (+) and removed (-) entries
+func printDiff(w io.Writer, r *diff.Result) {
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", r.From.Name(), r.To.Name())
+	if r.Empty() {
+		fmt.Fprintln(w, "\nNo differences")
+		return
+	}
+
+	printValue(w, "Base image", r.BaseImage)
+	printValue(w, "Builder image", r.BuilderImage)
+	printList(w, "Extensions", r.Extensions)
+	printList(w, "Dev extensions", r.DevExtensions)
+	if len(r.PECLVersions) > 0 {
+		fmt.Fprintln(w, "\nPECL versions")
+		for _, change := range r.PECLVersions {
+			fmt.Fprintf(w, "  ~ %s: %s -> %s\n", change.Name, orDefault(change.From, "latest"), orDefault(change.To, "latest"))
+		}
+	}
+	printList(w, "Build dependencies", r.BuildDeps)
+	printList(w, "Runtime dependencies", r.RuntimeDeps)
+	printValue(w, "Port", r.Port)
+	printList(w, "Process", r.Process)
+	printValue(w, "RoadRunner", r.RoadRunner)
+	printList(w, "Nginx server block", r.Nginx)
+	printValue(w, "Healthcheck", r.Healthcheck)
+	printList(w, "Conflicts", r.Conflicts)
+	printList(w, "Issues", r.Issues)
+}
+
+// printValue prints a changed value, if any
+func printValue(w io.Writer, title string, change *diff.Change) {
+	if change == nil {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n  - %s\n  + %s\n", title, orDefault(change.From, "(none)"), orDefault(change.To, "(none)"))
+}
+
+// printList prints the added and removed entries of a list, if any
+func printList(w io.Writer, title string, change *diff.ListChange) {
+	if change == nil {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n", title)
+	for _, entry := range change.Added {
+		fmt.Fprintf(w, "  + %s\n", entry)
+	}
+	for _, entry := range change.Removed {
+		fmt.Fprintf(w, "  - %s\n", entry)
+	}
+}
+
+// orDefault returns value, or def if value is empty
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
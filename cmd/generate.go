@@ -1,12 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 
+	"vess/internal/cache"
 	"vess/internal/config"
+	"vess/internal/docker"
+	"vess/internal/extensions"
 	"vess/internal/generator"
+	"vess/internal/lock"
 	"vess/internal/logger"
 
 	"github.com/spf13/cobra"
@@ -16,6 +23,19 @@ var (
 	envFile    string
 	outputFile string
 	imageType  string
+	renderMode string
+	genCache   string
+	noExtCache bool
+	genArch    string
+	templates  string
+	baseImage  string
+	registry   string
+	baseDigest string
+	lockMode   bool
+	lockFile   string
+	resolver   string
+	frozen     bool
+	noLock     bool
 )
 
 var generateCmd = &cobra.Command{
@@ -27,10 +47,66 @@ The env file should contain PHP extensions in the format:
   PHP_EXTENSIONS=mysqli,pdo_mysql,gd,redis,opcache
   
 The generated Dockerfile will include all necessary system dependencies
-and installation commands for the specified OS, PHP version, and image type.`,
+and installation commands for the specified OS, PHP version, and image type.
+
+With --mode optimized, the Dockerfile uses the BuildKit frontend
+(# syntax=docker/dockerfile:1): apk/apt and PECL downloads are kept in
+cache mounts, and extensions are compiled by a single
+docker-php-ext-install -j$(nproc) call. "vess build" builds such
+Dockerfiles with docker buildx (or podman). The default --mode compat
+keeps one RUN per extension and works with the classic builder.
+
+Extensions found in the extension cache (see "vess cache") for the target
+--arch are copied into the image instead of being compiled. Their files
+are written to .vess/ext next to the Dockerfile, which "vess build" sends
+with the build context. Use --no-ext-cache to compile everything.
+
+When the env file lists PHP_DEV_EXTENSIONS or PHP_DEV_INI settings, the
+Dockerfile has two targets: "prod" (the default) and "dev", which adds the
+dev-only extensions and settings. Select one with "vess build --target".
+
+--templates (or TEMPLATES_DIR in the env file) points to a directory of
+*.tmpl files layered over the built-in templates. Override whole files
+(alpine.dockerfile.tmpl) or single blocks: header, build-deps, extensions,
+final-stage and entrypoint, either for one OS ({{define "alpine.header"}})
+or for all of them ({{define "header"}}).
+
+When xdebug is installed, XDEBUG_MODE, XDEBUG_CLIENT_HOST and
+XDEBUG_CLIENT_PORT configure it (defaults: debug, host.docker.internal,
+9003). The mode can be changed at runtime with docker run -e XDEBUG_MODE=...
+
+The nginx-fpm type adds nginx to the fpm image, serving NGINX_DOCUMENT_ROOT
+and passing PHP requests to php-fpm, with both processes supervised by
+PROCESS_MANAGER (supervisord by default).
+
+The application server types frankenphp (PHP 8.2+), roadrunner and swoole
+run long-lived PHP workers. roadrunner needs the sockets extension and
+swoole the swoole extension in PHP_EXTENSIONS.
+
+--base-image (BASE_IMAGE) replaces the official php base image, e.g. with a
+hardened base; BASE_OS and BASE_INSTALLERS declare its OS family and the
+tools it provides. --registry (BASE_REGISTRY) pulls the official images
+from a mirror, and --base-digest (BASE_IMAGE_DIGEST) pins the base image.
+
+With --lock, the base images are pinned to the digests recorded in the lock
+file (vess.lock), and PECL extensions and OS packages to the recorded
+versions, so that later generations reproduce the same Dockerfile. Once the
+lock file exists, it is used without --lock; pass --no-lock to ignore it.
+Missing digests are resolved through the registry API (--resolver registry)
+or the container engine (--resolver daemon), package versions by running
+the base image, and added to the lock file, which also records the vess and
+extension registry versions. In CI, --frozen fails instead of changing the
+lock file. Refresh digests with "vess update".`,
 	Example: `  vess generate --os alpine --php-version 8.2 --type fpm --env-file app.env --output Dockerfile
   vess generate -o ubuntu -p 8.3 --type apache -e config.env -f Dockerfile.apache
-  vess generate -o alpine -p 8.3 --type cli -e worker.env -f Dockerfile.worker`,
+  vess generate -o alpine -p 8.3 --type cli -e worker.env -f Dockerfile.worker
+  vess generate -o alpine -p 8.3 --type nginx-fpm -e examples/nginx.env
+  vess generate -o alpine -p 8.3 --type swoole -e examples/octane.env
+  vess generate -o alpine -p 8.3 -e app.env --mode optimized
+  vess generate -o alpine -p 8.3 -e app.env --registry mirror.example.com/library
+  vess generate -o alpine -p 8.3 -e app.env --lock
+  vess generate -o alpine -p 8.3 -e app.env --frozen
+  vess generate -o alpine -p 8.3 -e examples/development.env`,
 	RunE: runGenerate,
 }
 
@@ -39,7 +115,20 @@ func init() {
 
 	generateCmd.Flags().StringVarP(&envFile, "env-file", "e", ".env", "Path to env file containing PHP extensions")
 	generateCmd.Flags().StringVarP(&outputFile, "output", "f", "Dockerfile", "Output path for generated Dockerfile")
-	generateCmd.Flags().StringVarP(&imageType, "type", "t", "fpm", "PHP base image type (cli, fpm, apache)")
+	generateCmd.Flags().StringVarP(&imageType, "type", "t", "fpm", "Image type ("+strings.Join(extensions.GetImageTypeNames(), ", ")+")")
+	generateCmd.Flags().StringVar(&renderMode, "mode", generator.ModeCompat, "Rendering mode (compat, optimized)")
+	generateCmd.Flags().StringVar(&genCache, "cache-dir", cache.DefaultDir(), "Extension cache directory")
+	generateCmd.Flags().BoolVar(&noExtCache, "no-ext-cache", false, "Compile all extensions instead of using cached builds")
+	generateCmd.Flags().StringVar(&genArch, "arch", runtime.GOARCH, "Target architecture used to look up cached extensions")
+	generateCmd.Flags().StringVar(&templates, "templates", "", "Directory of custom templates layered over the built-in ones (overrides TEMPLATES_DIR)")
+	generateCmd.Flags().StringVar(&baseImage, "base-image", "", "Base image of the final stage (overrides BASE_IMAGE)")
+	generateCmd.Flags().StringVar(&registry, "registry", "", "Registry prefix of the default base images, e.g. a mirror (overrides BASE_REGISTRY)")
+	generateCmd.Flags().StringVar(&baseDigest, "base-digest", "", "Pin the base image to this sha256 digest (overrides BASE_IMAGE_DIGEST)")
+	generateCmd.Flags().BoolVar(&lockMode, "lock", false, "Pin base image digests, PECL and OS package versions from the lock file")
+	generateCmd.Flags().StringVar(&lockFile, "lock-file", lock.FileName, "Path of the lock file")
+	generateCmd.Flags().BoolVar(&frozen, "frozen", false, "Fail if the lock file is missing entries or out of date instead of updating it (implies --lock)")
+	generateCmd.Flags().BoolVar(&noLock, "no-lock", false, "Ignore an existing lock file")
+	generateCmd.Flags().StringVar(&resolver, "resolver", docker.ResolverRegistry, "How --lock resolves digests (registry, daemon)")
 	generateCmd.MarkFlagRequired("env-file")
 }
 
@@ -47,12 +136,13 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	log := logger.New(IsVerbose())
 
 	log.Info("Starting Dockerfile generation")
-	log.Debug("OS: %s, PHP Version: %s, Type: %s", GetOSType(), GetPHPVersion(), imageType)
+	log.Debug("OS: %s, PHP Version: %s, Type: %s, Mode: %s", GetOSType(), GetPHPVersion(), imageType, renderMode)
 	log.Info("Parsing configuration file...")
 	cfg, err := config.ParseEnvFile(envFile)
 	if err != nil {
 		return fmt.Errorf("failed to parse env file: %w", err)
 	}
+	applyBaseImage(cfg, baseImage, registry, baseDigest)
 
 	// Validate configuration
 	log.Info("Validating extensions...")
@@ -60,15 +150,48 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	if err := validator.Validate(cfg, GetOSType(), GetPHPVersion(), imageType); err != nil {
 		return fmt.Errorf("validation failed: %w", err)
 	}
+	for _, warning := range validator.Warnings(cfg) {
+		log.Warn("%s", warning)
+	}
 
 	// Generate Dockerfile
 	log.Info("Generating Dockerfile...")
 	gen := generator.New(GetOSType(), GetPHPVersion(), imageType)
-	content, err := gen.Generate(cfg.Extensions)
+	if err := gen.SetMode(renderMode); err != nil {
+		return err
+	}
+	if dir := templatesDir(templates, cfg); dir != "" {
+		log.Debug("Using custom templates from %s", dir)
+		if err := gen.SetTemplates(dir); err != nil {
+			return err
+		}
+	}
+	useLock, err := useLockFile(log)
+	if err != nil {
+		return err
+	}
+	if useLock {
+		if err := generateLocked(cmd.Context(), log, gen, cfg); err != nil {
+			return err
+		}
+	}
+	if !noExtCache {
+		gen.SetCache(cache.New(genCache), genArch)
+	}
+	content, err := gen.Generate(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to generate Dockerfile: %w", err)
 	}
 
+	// Stage cached extensions for the build context
+	cached := gen.CachedEntries()
+	for _, entry := range cached {
+		log.Info("Using cached %s %s (%s)", entry.Extension, entry.Version, entry.Key())
+	}
+	if err := cache.Export(cached, filepath.Join(filepath.Dir(outputFile), generator.CacheContextDir)); err != nil {
+		return fmt.Errorf("failed to stage cached extensions: %w", err)
+	}
+
 	// Write to file
 	if err := os.WriteFile(outputFile, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to write Dockerfile: %w", err)
@@ -76,6 +199,80 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 
 	log.Success("Dockerfile generated successfully: %s", outputFile)
 	log.Info("Extensions included: %s", strings.Join(cfg.Extensions, ", "))
+	if len(cfg.DevExtensions) > 0 {
+		log.Info("Dev target extensions: %s", strings.Join(cfg.DevExtensions, ", "))
+	}
 
 	return nil
 }
+
+// useLockFile reports whether generation is pinned to the lock file: with
+// --lock or --frozen, or by default when the lock file exists
+func useLockFile(log *logger.Logger) (bool, error) {
+	if noLock {
+		if lockMode || frozen {
+			return false, fmt.Errorf("--no-lock cannot be combined with --lock or --frozen")
+		}
+		return false, nil
+	}
+	if lockMode || frozen {
+		return true, nil
+	}
+	if _, err := os.Stat(lockFile); err != nil {
+		return false, nil
+	}
+	log.Info("Using lock file %s (pass --no-lock to ignore it)", lockFile)
+	return true, nil
+}
+
+// generateLocked pins gen and cfg to the lock file and saves newly resolved
+// entries. With --frozen, it fails instead if the lock file would change.
+func generateLocked(ctx context.Context, log *logger.Logger, gen *generator.Generator, cfg *extensions.Config) error {
+	lk, err := lock.Load(lockFile)
+	if err != nil {
+		return err
+	}
+	res, err := newDigestResolver(ctx, log, resolver)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	l := &locker{ctx: ctx, log: log, lock: lk, resolver: res, frozen: frozen}
+	if err := l.Apply(gen, cfg, GetOSType(), GetPHPVersion()); err != nil {
+		return err
+	}
+	if frozen && len(l.changes) > 0 {
+		return fmt.Errorf("lock file %s is out of date (%s); run vess generate --lock to update it", lockFile, strings.Join(l.changes, "; "))
+	}
+	if frozen || (len(l.changes) == 0 && !l.upgraded) {
+		log.Debug("Lock file %s is up to date", lockFile)
+		return nil
+	}
+	if err := lk.Save(lockFile); err != nil {
+		return err
+	}
+	log.Info("Updated lock file %s", lockFile)
+	return nil
+}
+
+// applyBaseImage overrides the base image settings of cfg with non-empty flag values
+func applyBaseImage(cfg *extensions.Config, image, registry, digest string) {
+	if image != "" {
+		cfg.Base.Image = image
+	}
+	if registry != "" {
+		cfg.Base.Registry = registry
+	}
+	if digest != "" {
+		cfg.Base.Digest = strings.ToLower(digest)
+	}
+}
+
+// templatesDir returns the custom templates directory from the --templates flag or the env file
+func templatesDir(flag string, cfg *extensions.Config) string {
+	if flag != "" {
+		return flag
+	}
+	return cfg.TemplatesDir
+}
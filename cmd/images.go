@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"vess/internal/docker"
+	"vess/internal/generator"
+	"vess/internal/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	imagesFormat string
+)
+
+var imagesCmd = &cobra.Command{
+	Use:   "images",
+	Short: "List images built by vess",
+	Long: `List images built by vess together with the OS, PHP version, image type,
+extension list and config file they were generated from.
+
+Images are identified by the io.vess.* labels that generated Dockerfiles
+and vess build attach to every image.`,
+	Example: `  vess images
+  vess images --format json`,
+	RunE: runImages,
+}
+
+func init() {
+	rootCmd.AddCommand(imagesCmd)
+
+	imagesCmd.Flags().StringVar(&imagesFormat, "format", "table", "Output format (table, json)")
+}
+
+func runImages(cmd *cobra.Command, args []string) error {
+	log := logger.New(IsVerbose())
+
+	if imagesFormat != "table" && imagesFormat != "json" {
+		return fmt.Errorf("unsupported format: %s (must be 'table' or 'json')", imagesFormat)
+	}
+
+	client, err := connectDocker(cmd.Context(), log)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	builder := docker.NewBuilder(client, log)
+	images, err := builder.ListImages(cmd.Context(), generator.LabelManaged+"=true")
+	if err != nil {
+		return err
+	}
+	entries := docker.ExpandImages(images)
+
+	if imagesFormat == "json" {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printImageTable(entries)
+	return nil
+}
+
+// printImageTable prints vess images with their generation attributes
+func printImageTable(entries []*docker.ImageEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tTAG\tIMAGE ID\tOS\tPHP\tTYPE\tEXTENSIONS\tCONFIG\tCREATED\tSIZE")
+
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Repository,
+			entry.Tag,
+			shortID(entry.ID),
+			labelOrDash(entry.Labels, generator.LabelOS),
+			labelOrDash(entry.Labels, generator.LabelPHPVersion),
+			labelOrDash(entry.Labels, generator.LabelImageType),
+			truncate(labelOrDash(entry.Labels, generator.LabelExtensions), 40),
+			labelOrDash(entry.Labels, generator.LabelConfig),
+			entry.Created.Local().Format(time.DateTime),
+			docker.FormatSize(entry.Size),
+		)
+	}
+
+	w.Flush()
+}
+
+// shortID returns the first 12 hex characters of an image ID
+func shortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// labelOrDash returns a label value or "-" when it is not set
+func labelOrDash(labels map[string]string, key string) string {
+	if value := labels[key]; value != "" {
+		return value
+	}
+	return "-"
+}
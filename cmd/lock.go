@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"vess/internal/docker"
+	"vess/internal/extensions"
+	"vess/internal/generator"
+	"vess/internal/lock"
+	"vess/internal/logger"
+)
+
+// digestResolver resolves base image digests through the registry API or
+// the container engine, connecting to the engine on first use
+type digestResolver struct {
+	ctx    context.Context
+	log    *logger.Logger
+	mode   string
+	client *docker.Client
+}
+
+// newDigestResolver creates a resolver for mode (registry or daemon)
+func newDigestResolver(ctx context.Context, log *logger.Logger, mode string) (*digestResolver, error) {
+	switch mode {
+	case docker.ResolverRegistry, docker.ResolverDaemon:
+		return &digestResolver{ctx: ctx, log: log, mode: mode}, nil
+	default:
+		return nil, fmt.Errorf("unsupported resolver: %s (must be one of: %s, %s)", mode, docker.ResolverRegistry, docker.ResolverDaemon)
+	}
+}
+
+// Resolve returns the digest image currently points to
+func (r *digestResolver) Resolve(image string) (string, error) {
+	if r.mode == docker.ResolverRegistry {
+		return docker.ResolveDigest(r.ctx, image)
+	}
+	client, err := r.engine()
+	if err != nil {
+		return "", err
+	}
+	return client.ImageDigest(r.ctx, image)
+}
+
+// engine returns the container engine connection, connecting on first use
+func (r *digestResolver) engine() (*docker.Client, error) {
+	if r.client == nil {
+		client, err := connectDocker(r.ctx, r.log)
+		if err != nil {
+			return nil, err
+		}
+		r.client = client
+	}
+	return r.client, nil
+}
+
+// Close releases the engine connection, if any
+func (r *digestResolver) Close() {
+	if r.client != nil {
+		r.client.Close()
+	}
+}
+
+// locker pins generators to a lock file. Missing entries are resolved and
+// recorded; in frozen mode nothing is resolved and every entry the lock
+// would gain or change is collected in changes instead. A different vess
+// version is recorded too, but is not a change: it does not alter the
+// rendered Dockerfile's inputs.
+type locker struct {
+	ctx      context.Context
+	log      *logger.Logger
+	lock     *lock.Lock
+	resolver *digestResolver
+	frozen   bool
+	changes  []string
+	upgraded bool // the lock was written by another vess version
+}
+
+// Apply pins the base images and OS packages of gen and the PECL extensions
+// of cfg to the lock. Versions pinned in PECL_VERSIONS take precedence over
+// the lock.
+func (l *locker) Apply(gen *generator.Generator, cfg *extensions.Config, osType, phpVersion string) error {
+	if l.lock.Vess != rootCmd.Version {
+		l.log.Debug("Lock file written by vess %q, recording %q", l.lock.Vess, rootCmd.Version)
+		l.lock.Vess = rootCmd.Version
+		l.upgraded = true
+	}
+	if registryVersion := extensions.RegistryVersion(); l.lock.Registry != registryVersion {
+		l.change("extension registry %q -> %q", l.lock.Registry, registryVersion)
+		l.lock.Registry = registryVersion
+	}
+
+	inputs, err := gen.Inputs(cfg)
+	if err != nil {
+		return err
+	}
+	for _, image := range inputs.Images {
+		if _, ok := l.lock.Images[image]; ok {
+			continue
+		}
+		if l.change("image %s", image) {
+			continue
+		}
+		digest, err := l.resolver.Resolve(image)
+		if err != nil {
+			return fmt.Errorf("failed to resolve digest of %s: %w", image, err)
+		}
+		l.lock.Images[image] = digest
+		l.log.Info("Locked %s to %s", image, digest)
+	}
+	gen.SetDigests(l.lock.Images)
+
+	if err := l.lockPackages(inputs.Packages, osType); err != nil {
+		return err
+	}
+	gen.SetPackageVersions(l.lock.Packages)
+
+	return l.lockPECL(cfg, osType, phpVersion)
+}
+
+// lockPackages resolves the versions of OS packages missing from the lock by
+// running their base image
+func (l *locker) lockPackages(packages map[string][]string, osType string) error {
+	images := make([]string, 0, len(packages))
+	for image := range packages {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+
+	for _, image := range images {
+		var missing []string
+		for _, pkg := range packages[image] {
+			if _, ok := l.lock.PackageVersion(image, pkg); !ok {
+				missing = append(missing, pkg)
+			}
+		}
+		if len(missing) == 0 || l.change("packages %s on %s", strings.Join(missing, ", "), image) {
+			continue
+		}
+
+		client, err := l.resolver.engine()
+		if err != nil {
+			return fmt.Errorf("resolving package versions needs a container engine: %w", err)
+		}
+		pinned := image
+		if digest := l.lock.Images[image]; digest != "" {
+			pinned = image + "@" + digest
+		}
+		l.log.Info("Resolving %d package versions on %s...", len(missing), pinned)
+		if err := docker.NewBuilder(client, l.log).Pull(l.ctx, pinned, nil); err != nil {
+			return err
+		}
+		versions, err := client.PackageVersions(l.ctx, pinned, extensions.GetPackageManager(osType), missing)
+		if err != nil {
+			return err
+		}
+		for pkg, version := range versions {
+			l.lock.SetPackageVersion(image, pkg, version)
+			l.log.Debug("Locked package %s to %s", pkg, version)
+		}
+	}
+	return nil
+}
+
+// lockPECL pins the PECL extensions of cfg for phpVersion
+func (l *locker) lockPECL(cfg *extensions.Config, osType, phpVersion string) error {
+	for _, extName := range cfg.TargetExtensions(extensions.TargetDev) {
+		ext, ok := extensions.GetExtension(extName)
+		if !ok || ext.OSSupport[osType] == nil || !ext.OSSupport[osType].PECLInstall {
+			continue
+		}
+		locked, ok := l.lock.PECLVersion(phpVersion, extName)
+		if version := cfg.PECLVersions[extName]; version != "" {
+			if locked != version {
+				l.change("PECL %s %q -> %q", extName, locked, version)
+				l.lock.SetPECLVersion(phpVersion, extName, version)
+			}
+			continue
+		}
+		if !ok {
+			if l.change("PECL %s for PHP %s", extName, phpVersion) {
+				continue
+			}
+			version, err := lock.PECLVersion(l.ctx, extName, phpVersion)
+			if err != nil {
+				return fmt.Errorf("failed to resolve PECL version of %s: %w", extName, err)
+			}
+			l.lock.SetPECLVersion(phpVersion, extName, version)
+			l.log.Info("Locked PECL %s to %s", extName, version)
+			locked = version
+		}
+		cfg.PECLVersions[extName] = locked
+	}
+	return nil
+}
+
+// change records an entry the lock gains or changes and reports whether the
+// locker is frozen, in which case the entry must not be resolved
+func (l *locker) change(format string, args ...interface{}) bool {
+	l.changes = append(l.changes, fmt.Sprintf(format, args...))
+	return l.frozen
+}
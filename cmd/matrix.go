@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"vess/internal/config"
+	"vess/internal/docker"
+	"vess/internal/generator"
+	"vess/internal/logger"
+	"vess/internal/matrix"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	matrixOSTypes     []string
+	matrixPHPVersions []string
+	matrixTypes       []string
+	matrixEnvFile     string
+	matrixOutputDir   string
+	matrixBuild       bool
+	matrixTagPrefix   string
+	matrixJobs        int
+	matrixMode        string
+	matrixTemplates   string
+	matrixRegistry    string
+)
+
+var matrixCmd = &cobra.Command{
+	Use:   "matrix",
+	Short: "Generate and build many OS × PHP × type combinations",
+	Long: `Generate a Dockerfile for every combination of the given OS types, PHP
+versions and image types using one extension config.
+
+Combinations that are not available (for example alpine with apache, or
+an extension that does not support a PHP version) are skipped with the
+reason. Other config errors, such as an unknown extension or OS, fail the
+combination, and the command fails when a combination failed or none was
+generated. Each Dockerfile is written to --output-dir as Dockerfile.<name>,
+where <name> follows the official php tag layout (e.g. 8.3-fpm-alpine).
+
+With --build, the images are built concurrently, at most --jobs at a time,
+and tagged <tag-prefix>:<name>. Build output goes to <name>.log in the
+output directory, and a summary table with status, size and duration is
+printed at the end. Use --mode optimized to render BuildKit Dockerfiles
+(see "vess generate").`,
+	Example: `  vess matrix -e examples/basic.env
+  vess matrix -e app.env --oses alpine --php-versions 8.2,8.3 --types cli,fpm
+  vess matrix -e app.env --build --jobs 4 --tag-prefix registry.example.com/php-base`,
+	RunE: runMatrix,
+}
+
+func init() {
+	rootCmd.AddCommand(matrixCmd)
+
+	matrixCmd.Flags().StringSliceVar(&matrixOSTypes, "oses", []string{"alpine", "ubuntu"}, "OS types to include")
+	matrixCmd.Flags().StringSliceVar(&matrixPHPVersions, "php-versions", []string{"7.4", "8.0", "8.1", "8.2", "8.3"}, "PHP versions to include")
+	matrixCmd.Flags().StringSliceVar(&matrixTypes, "types", []string{"cli", "fpm", "apache"}, "Image types to include")
+	matrixCmd.Flags().StringVarP(&matrixEnvFile, "env-file", "e", ".env", "Path to env file containing PHP extensions")
+	matrixCmd.Flags().StringVar(&matrixOutputDir, "output-dir", "matrix", "Directory for generated Dockerfiles and build logs")
+	matrixCmd.Flags().BoolVar(&matrixBuild, "build", false, "Build every generated Dockerfile")
+	matrixCmd.Flags().StringVar(&matrixTagPrefix, "tag-prefix", "vess-php", "Image repository used to tag built images")
+	matrixCmd.Flags().IntVarP(&matrixJobs, "jobs", "j", 2, "Maximum number of concurrent builds")
+	matrixCmd.Flags().StringVar(&matrixMode, "mode", generator.ModeCompat, "Rendering mode (compat, optimized)")
+	matrixCmd.Flags().StringVar(&matrixRegistry, "registry", "", "Registry prefix of the base images, e.g. a mirror (overrides BASE_REGISTRY)")
+	matrixCmd.Flags().StringVar(&matrixTemplates, "templates", "", "Directory of custom templates layered over the built-in ones (overrides TEMPLATES_DIR)")
+	matrixCmd.MarkFlagRequired("env-file")
+}
+
+func runMatrix(cmd *cobra.Command, args []string) error {
+	log := logger.New(IsVerbose())
+
+	if matrixJobs < 1 {
+		return fmt.Errorf("--jobs must be at least 1")
+	}
+
+	cfg, err := config.ParseEnvFile(matrixEnvFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse env file: %w", err)
+	}
+	applyBaseImage(cfg, "", matrixRegistry, "")
+	// A single base image cannot serve every OS, PHP version and type
+	if cfg.Base.Image != "" || cfg.Base.Digest != "" {
+		return fmt.Errorf("BASE_IMAGE and BASE_IMAGE_DIGEST cannot be used with vess matrix; use BASE_REGISTRY or --registry")
+	}
+
+	if err := os.MkdirAll(matrixOutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	combinations := matrix.Expand(matrix.Axes{
+		OSTypes:     matrixOSTypes,
+		PHPVersions: matrixPHPVersions,
+		ImageTypes:  matrixTypes,
+	}, cfg)
+
+	// Generate Dockerfiles
+	for _, c := range combinations {
+		if c.Status == matrix.StatusSkipped {
+			log.Debug("Skipping %s: %s", c.Name(), c.Reason)
+			continue
+		}
+		if c.Status == matrix.StatusFailed {
+			log.Error("Invalid %s: %s", c.Name(), c.Reason)
+			continue
+		}
+
+		gen := generator.New(c.OSType, c.PHPVersion, c.ImageType)
+		if err := gen.SetMode(matrixMode); err != nil {
+			return err
+		}
+		if dir := templatesDir(matrixTemplates, cfg); dir != "" {
+			if err := gen.SetTemplates(dir); err != nil {
+				return err
+			}
+		}
+		content, err := gen.Generate(cfg)
+		if err != nil {
+			c.Status = matrix.StatusFailed
+			c.Reason = err.Error()
+			continue
+		}
+
+		c.Dockerfile = filepath.Join(matrixOutputDir, "Dockerfile."+c.Name())
+		if err := os.WriteFile(c.Dockerfile, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write Dockerfile: %w", err)
+		}
+		c.Status = matrix.StatusGenerated
+		log.Info("Generated %s", c.Dockerfile)
+	}
+
+	if matrixBuild {
+		if err := buildMatrix(cmd.Context(), log, combinations); err != nil {
+			return err
+		}
+	}
+
+	printMatrixSummary(combinations)
+
+	counts := matrix.Summary(combinations)
+	log.Info("%d generated, %d passed, %d failed, %d skipped",
+		counts[matrix.StatusGenerated], counts[matrix.StatusPassed], counts[matrix.StatusFailed], counts[matrix.StatusSkipped])
+	if counts[matrix.StatusFailed] > 0 {
+		return fmt.Errorf("%d matrix combination(s) failed", counts[matrix.StatusFailed])
+	}
+	if counts[matrix.StatusGenerated]+counts[matrix.StatusPassed] == 0 {
+		return fmt.Errorf("no matrix combination was generated")
+	}
+
+	return nil
+}
+
+// buildMatrix builds the generated combinations with at most matrixJobs builds in flight
+func buildMatrix(ctx context.Context, log *logger.Logger, combinations []*matrix.Combination) error {
+	client, err := connectDocker(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	builder := docker.NewBuilder(client, log)
+	sem := make(chan struct{}, matrixJobs)
+	var wg sync.WaitGroup
+
+	for _, c := range combinations {
+		if c.Status != matrix.StatusGenerated {
+			continue
+		}
+
+		wg.Add(1)
+		go func(c *matrix.Combination) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			c.Tag = fmt.Sprintf("%s:%s", matrixTagPrefix, c.Name())
+			log.Info("Building %s", c.Tag)
+			buildCombination(ctx, builder, c)
+			if c.Status == matrix.StatusPassed {
+				log.Success("Built %s in %s", c.Tag, c.Duration.Round(time.Second))
+			} else {
+				log.Error("Failed %s: %s", c.Tag, c.Reason)
+			}
+		}(c)
+	}
+
+	wg.Wait()
+	return ctx.Err()
+}
+
+// buildCombination builds one combination, logging its output to a file
+func buildCombination(ctx context.Context, builder *docker.Builder, c *matrix.Combination) {
+	started := time.Now()
+	defer func() { c.Duration = time.Since(started) }()
+
+	logPath := filepath.Join(matrixOutputDir, c.Name()+".log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		c.Status, c.Reason = matrix.StatusFailed, err.Error()
+		return
+	}
+	defer logFile.Close()
+
+	renderer, _ := docker.NewProgressRenderer(docker.ProgressPlain, logFile)
+	result, err := builder.Build(ctx, docker.BuildOptions{
+		Dockerfile: c.Dockerfile,
+		Tag:        c.Tag,
+		Labels: map[string]string{
+			generator.LabelManaged:    "true",
+			generator.LabelDockerfile: c.Dockerfile,
+		},
+		Progress: renderer,
+	})
+	if err != nil {
+		c.Status = matrix.StatusFailed
+		c.Reason = fmt.Sprintf("%v (see %s)", err, logPath)
+		return
+	}
+
+	c.Status = matrix.StatusPassed
+	c.ImageID = result.ImageID
+	if meta, err := builder.InspectImage(ctx, c.Tag); err == nil {
+		c.Size = meta.Size
+	}
+}
+
+// printMatrixSummary prints one row per combination
+func printMatrixSummary(combinations []*matrix.Combination) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OS\tIMAGE\tSTATUS\tSIZE\tDURATION\tDETAIL")
+
+	for _, c := range combinations {
+		size, duration, detail := "-", "-", c.Reason
+		if c.Size > 0 {
+			size = docker.FormatSize(c.Size)
+		}
+		if c.Duration > 0 {
+			duration = c.Duration.Round(time.Second).String()
+		}
+		if detail == "" {
+			detail = c.Dockerfile
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", c.OSType, c.Name(), c.Status, size, duration, truncate(detail, 100))
+	}
+
+	w.Flush()
+}
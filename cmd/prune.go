@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+
+	"vess/internal/docker"
+	"vess/internal/generator"
+	"vess/internal/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneOlderThan string
+	pruneKeep      int
+	pruneTag       string
+	pruneDangling  bool
+	pruneDryRun    bool
+	pruneForce     bool
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old or dangling images built by vess",
+	Long: `Remove images built by vess.
+
+Images are selected when they match any of --older-than, --keep or
+--dangling. --tag restricts the candidates to repository:tag values matching
+a glob pattern; used on its own it selects every matching image.
+
+Tagged images are untagged one reference at a time, so an image shared by
+several tags is only deleted once its last selected tag is removed.`,
+	Example: `  vess prune --dangling
+  vess prune --older-than 14d --dry-run
+  vess prune --keep 3
+  vess prune --tag 'registry.example.com/php:8.1-*'`,
+	RunE: runPrune,
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	pruneCmd.Flags().StringVar(&pruneOlderThan, "older-than", "", "Remove images older than this age (e.g., 72h, 14d)")
+	pruneCmd.Flags().IntVar(&pruneKeep, "keep", 0, "Keep only the newest N tags per repository")
+	pruneCmd.Flags().StringVar(&pruneTag, "tag", "", "Only consider repository:tag values matching this glob pattern")
+	pruneCmd.Flags().BoolVar(&pruneDangling, "dangling", false, "Remove untagged vess images")
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Show what would be removed without removing anything")
+	pruneCmd.Flags().BoolVar(&pruneForce, "force", false, "Force removal of images used by stopped containers")
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	log := logger.New(IsVerbose())
+
+	opts := docker.PruneOptions{
+		KeepPerRepo: pruneKeep,
+		TagPattern:  pruneTag,
+		Dangling:    pruneDangling,
+	}
+	if pruneOlderThan != "" {
+		age, err := docker.ParseAge(pruneOlderThan)
+		if err != nil {
+			return err
+		}
+		opts.OlderThan = age
+	}
+	if opts.OlderThan == 0 && opts.KeepPerRepo == 0 && !opts.Dangling && opts.TagPattern == "" {
+		return fmt.Errorf("specify at least one of --older-than, --keep, --tag or --dangling")
+	}
+
+	client, err := connectDocker(cmd.Context(), log)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := cmd.Context()
+	builder := docker.NewBuilder(client, log)
+	images, err := builder.ListImages(ctx, generator.LabelManaged+"=true")
+	if err != nil {
+		return err
+	}
+
+	selected, err := docker.SelectForPrune(docker.ExpandImages(images), opts)
+	if err != nil {
+		return err
+	}
+	if len(selected) == 0 {
+		log.Info("No vess images to remove")
+		return nil
+	}
+
+	var reclaimed int64
+	count := 0
+	removed := make(map[string]bool)
+	for _, entry := range selected {
+		if pruneDryRun {
+			log.Info("Would remove %s (%s, created %s)", entry.Reference(), shortID(entry.ID), entry.Created.Local().Format("2006-01-02"))
+			continue
+		}
+
+		log.Info("Removing %s", entry.Reference())
+		if err := builder.RemoveImage(ctx, entry.Reference(), pruneForce); err != nil {
+			log.Error("%s: %v", entry.Reference(), err)
+			continue
+		}
+		count++
+		if !removed[entry.ID] {
+			removed[entry.ID] = true
+			reclaimed += entry.Size
+		}
+	}
+
+	if pruneDryRun {
+		log.Info("Dry run: %d image reference(s) selected", len(selected))
+		return nil
+	}
+
+	log.Success("Removed %d image reference(s), up to %s reclaimed", count, docker.FormatSize(reclaimed))
+	return nil
+}
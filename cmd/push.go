@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"vess/internal/docker"
+	"vess/internal/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	pushTag      string
+	pushProgress string
+)
+
+var pushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Push an image to a registry",
+	Long: `Push a built image to its registry and report the pushed digest.
+
+Credentials are resolved in this order:
+  1. VESS_REGISTRY_USERNAME and VESS_REGISTRY_PASSWORD environment variables
+  2. A credential helper configured for the registry (credHelpers)
+  3. The default credential store (credsStore)
+  4. The auths section of ~/.docker/config.json (or $DOCKER_CONFIG)
+
+Registries without authentication, such as a local registry:2 instance,
+are pushed to anonymously.`,
+	Example: `  vess push --tag registry.example.com/team/php:8.3
+  vess push -t localhost:5000/my-php:8.3 --progress plain`,
+	RunE: runPush,
+}
+
+func init() {
+	rootCmd.AddCommand(pushCmd)
+
+	pushCmd.Flags().StringVarP(&pushTag, "tag", "t", "", "Image reference to push (e.g., registry.example.com/my-php:8.3)")
+	pushCmd.Flags().StringVar(&pushProgress, "progress", docker.ProgressAuto, "Progress output (auto, plain, tty, json)")
+	pushCmd.MarkFlagRequired("tag")
+}
+
+func runPush(cmd *cobra.Command, args []string) error {
+	log := logger.New(IsVerbose())
+
+	renderer, err := docker.NewProgressRenderer(pushProgress, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if pushProgress == docker.ProgressJSON {
+		log.SetOutput(os.Stderr)
+	}
+
+	client, err := connectDocker(cmd.Context(), log)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	_, err = pushImage(cmd.Context(), log, docker.NewBuilder(client, log), pushTag, renderer)
+	return err
+}
+
+// pushImage pushes imageRef and logs the resulting digest
+func pushImage(ctx context.Context, log *logger.Logger, builder *docker.Builder, imageRef string, renderer docker.ProgressRenderer) (*docker.PushResult, error) {
+	log.Info("Pushing image: %s", imageRef)
+	result, err := builder.Push(ctx, imageRef, renderer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to push image: %w", err)
+	}
+
+	log.Success("Pushed %s", imageRef)
+	log.Info("Digest: %s (%s)", result.Digest, docker.FormatSize(result.Size))
+
+	return result, nil
+}
@@ -1,8 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+
+	"vess/internal/docker"
+	"vess/internal/logger"
 
 	"github.com/spf13/cobra"
 )
@@ -12,6 +18,7 @@ var (
 	osType     string
 	phpVersion string
 	verbose    bool
+	dockerHost string
 )
 
 var rootCmd = &cobra.Command{
@@ -24,8 +31,12 @@ Supports PHP 7.4+ with Alpine and Ubuntu base images.`,
 	Version: "1.1.1",
 }
 
+// Execute runs the root command with a context that is cancelled on SIGINT/SIGTERM
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return rootCmd.ExecuteContext(ctx)
 }
 
 func init() {
@@ -33,6 +44,7 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&osType, "os", "o", "alpine", "Operating system (alpine, ubuntu)")
 	rootCmd.PersistentFlags().StringVarP(&phpVersion, "php-version", "p", "8.3", "PHP version (7.4, 8.0, 8.1, 8.2, 8.3)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().StringVar(&dockerHost, "host", "", "Container engine endpoint (e.g., unix:///run/user/1000/podman/podman.sock, ssh://user@host); defaults to DOCKER_HOST or the active docker context")
 }
 
 // GetOSType returns the configured OS type
@@ -50,6 +62,28 @@ func IsVerbose() bool {
 	return verbose
 }
 
+// connectDocker creates a Docker client for the configured endpoint and checks the engine
+func connectDocker(ctx context.Context, log *logger.Logger) (*docker.Client, error) {
+	client, err := docker.NewClient(dockerHost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker client: %w", err)
+	}
+
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	engine, err := client.Engine(ctx)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	log.Debug("Engine: %s", engine)
+
+	return client, nil
+}
+
 // PrintError prints an error message and exits
 func PrintError(err error) {
 	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
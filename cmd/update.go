@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"vess/internal/docker"
+	"vess/internal/lock"
+	"vess/internal/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	updateLockFile string
+	updateResolver string
+	updateDryRun   bool
+	updatePECL     bool
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Re-resolve the base image digests of the lock file",
+	Long: `Resolve every base image recorded in the lock file (see "vess generate
+--lock") again and report which ones changed, e.g. because the tag was
+rebuilt with security fixes. The lock file is updated unless --dry-run is
+given; regenerate the Dockerfiles with --lock to pick up the new digests.
+The OS package versions locked for a changed image are dropped and resolved
+again on the new image by the next generation.
+
+With --pecl, the locked PECL extension versions are refreshed as well, to
+the newest stable release that supports each locked PHP version.`,
+	Example: `  vess update
+  vess update --dry-run
+  vess update --lock-file build/vess.lock --resolver daemon --pecl`,
+	RunE: runUpdate,
+}
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+
+	updateCmd.Flags().StringVar(&updateLockFile, "lock-file", lock.FileName, "Path of the lock file")
+	updateCmd.Flags().StringVar(&updateResolver, "resolver", docker.ResolverRegistry, "How digests are resolved (registry, daemon)")
+	updateCmd.Flags().BoolVar(&updateDryRun, "dry-run", false, "Report changes without writing the lock file")
+	updateCmd.Flags().BoolVar(&updatePECL, "pecl", false, "Also refresh the locked PECL extension versions")
+}
+
+func runUpdate(cmd *cobra.Command, args []string) error {
+	log := logger.New(IsVerbose())
+	ctx := cmd.Context()
+
+	if _, err := os.Stat(updateLockFile); err != nil {
+		return fmt.Errorf("failed to open lock file: %w", err)
+	}
+	lk, err := lock.Load(updateLockFile)
+	if err != nil {
+		return err
+	}
+	res, err := newDigestResolver(ctx, log, updateResolver)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ENTRY\tSTATUS\tLOCKED\tCURRENT")
+	total, changed := 0, 0
+
+	for _, image := range sortedKeys(lk.Images) {
+		digest, err := res.Resolve(image)
+		if err != nil {
+			return fmt.Errorf("failed to resolve digest of %s: %w", image, err)
+		}
+		total++
+		if digest != lk.Images[image] {
+			changed++
+			// Packages are resolved again on the new image by the next generate --lock
+			delete(lk.Packages, image)
+		}
+		printUpdate(w, image, lk.Images[image], digest)
+		lk.Images[image] = digest
+	}
+
+	if updatePECL {
+		for _, phpVersion := range sortedKeys(lk.PECL) {
+			for _, extName := range sortedKeys(lk.PECL[phpVersion]) {
+				latest, err := lock.PECLVersion(ctx, extName, phpVersion)
+				if err != nil {
+					return fmt.Errorf("failed to resolve PECL version of %s for PHP %s: %w", extName, phpVersion, err)
+				}
+				locked := lk.PECL[phpVersion][extName]
+				total++
+				if latest != locked {
+					changed++
+				}
+				printUpdate(w, fmt.Sprintf("pecl %s (PHP %s)", extName, phpVersion), locked, latest)
+				lk.SetPECLVersion(phpVersion, extName, latest)
+			}
+		}
+	}
+	w.Flush()
+
+	if changed == 0 {
+		log.Success("Lock file %s is up to date", updateLockFile)
+		return nil
+	}
+	if updateDryRun {
+		log.Info("%d of %d lock entries changed; lock file not written (--dry-run)", changed, total)
+		return nil
+	}
+	if err := lk.Save(updateLockFile); err != nil {
+		return err
+	}
+	log.Success("%d of %d lock entries changed in %s; regenerate with --lock to apply them", changed, total, updateLockFile)
+	return nil
+}
+
+// printUpdate prints one lock entry row
+func printUpdate(w *tabwriter.Writer, entry, locked, current string) {
+	status := "unchanged"
+	if locked != current {
+		status = "changed"
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry, status, locked, current)
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"vess/internal/config"
+	"vess/internal/logger"
+	"vess/internal/upgrade"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	upgradeEnvFile string
+	upgradeTo      string
+	upgradeType    string
+	upgradeOutput  string
+	upgradeFormat  string
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Plan moving a config to another PHP version",
+	Long: `Report what moving an env file from --php-version to the --to PHP version
+involves, using the PHP versions and PECL release data of the extension
+registry:
+
+  - extensions the target does not support (e.g. xmlrpc on PHP 8.1+) are
+    removed, with suggested alternatives
+  - PECL_VERSIONS pins older than the first release supporting the target
+    are raised to it
+  - base image, build and runtime dependency changes and new compatibility
+    issues are listed as in "vess diff"
+
+With --output, the updated env file is written, keeping the comments and
+layout of the original. Pass the env file itself to update it in place.`,
+	Example: `  vess upgrade -e app.env -p 7.4 --to 8.3
+  vess upgrade -e app.env -p 7.4 --to 8.3 --output app.env
+  vess upgrade -e app.env -p 8.1 --to 8.3 --format json`,
+	RunE: runUpgrade,
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+
+	upgradeCmd.Flags().StringVarP(&upgradeEnvFile, "env-file", "e", ".env", "Path to env file containing PHP extensions")
+	upgradeCmd.Flags().StringVar(&upgradeTo, "to", "", "Target PHP version")
+	upgradeCmd.Flags().StringVarP(&upgradeType, "type", "t", "fpm", "Image type")
+	upgradeCmd.Flags().StringVarP(&upgradeOutput, "output", "f", "", "Write the updated env file to this path")
+	upgradeCmd.Flags().StringVar(&upgradeFormat, "format", "text", "Output format (text, json)")
+	upgradeCmd.MarkFlagRequired("env-file")
+	upgradeCmd.MarkFlagRequired("to")
+}
+
+func runUpgrade(cmd *cobra.Command, args []string) error {
+	log := logger.New(IsVerbose())
+
+	if upgradeFormat != "text" && upgradeFormat != "json" {
+		return fmt.Errorf("unsupported format: %s (must be 'text' or 'json')", upgradeFormat)
+	}
+
+	content, err := os.ReadFile(upgradeEnvFile)
+	if err != nil {
+		return fmt.Errorf("failed to read env file: %w", err)
+	}
+	cfg, err := config.ParseEnvFile(upgradeEnvFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse env file: %w", err)
+	}
+
+	report, err := upgrade.Plan(cfg, GetOSType(), upgradeType, GetPHPVersion(), upgradeTo)
+	if err != nil {
+		return err
+	}
+
+	if upgradeFormat == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printUpgrade(os.Stdout, report)
+	}
+
+	if upgradeOutput == "" {
+		if upgradeFormat == "text" {
+			log.Info("Use --output to write the updated env file")
+		}
+		return nil
+	}
+	if len(report.Config.Extensions) == 0 {
+		return fmt.Errorf("no extension of PHP_EXTENSIONS supports PHP %s; not writing %s", upgradeTo, upgradeOutput)
+	}
+	if err := os.WriteFile(upgradeOutput, []byte(report.Rewrite(string(content))), 0644); err != nil {
+		return fmt.Errorf("failed to write env file: %w", err)
+	}
+	log.Success("Updated env file written to %s; generate with --php-version %s", upgradeOutput, upgradeTo)
+	return nil
+}
+
+// printUpgrade prints the migration report
+func printUpgrade(w io.Writer, r *upgrade.Report) {
+	fmt.Fprintf(w, "PHP %s -> %s: %s\n", r.From, r.To, r.Summary())
+
+	if len(r.Removed) > 0 {
+		fmt.Fprintln(w, "\nRemoved extensions")
+		for _, ext := range r.Removed {
+			fmt.Fprintf(w, "  - %s (%s): supports PHP %s\n", ext.Name, ext.Target, strings.Join(ext.PHPVersions, ", "))
+			for _, alternative := range ext.Alternatives {
+				fmt.Fprintf(w, "      alternative: %s\n", alternative)
+			}
+			if len(ext.Alternatives) == 0 {
+				fmt.Fprintf(w, "      no alternative known; stay on PHP %s or drop the extension\n", ext.PHPVersions[len(ext.PHPVersions)-1])
+			}
+		}
+	}
+
+	if len(r.PECL) > 0 {
+		fmt.Fprintln(w, "\nPECL versions")
+		for _, change := range r.PECL {
+			requirement := ""
+			if change.Required != "" {
+				requirement = fmt.Sprintf(" (PHP %s needs %s or newer)", r.To, change.Required)
+			}
+			switch change.Action {
+			case upgrade.ActionBump:
+				fmt.Fprintf(w, "  ^ %s: %s -> %s%s\n", change.Name, change.Pinned, change.Required, requirement)
+			case upgrade.ActionKeep:
+				fmt.Fprintf(w, "  = %s: %s%s\n", change.Name, change.Pinned, requirement)
+			default:
+				fmt.Fprintf(w, "  * %s: latest release%s\n", change.Name, requirement)
+			}
+		}
+	}
+
+	fmt.Fprintln(w, "\nChanges")
+	printDiff(w, r.Changes)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"vess/internal/config"
+	"vess/internal/docker"
+	"vess/internal/generator"
+	"vess/internal/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyImageRef string
+	verifyEnvFile  string
+	verifyTarget   string
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify that an image loads the configured PHP extensions",
+	Long: `Verify a built image against an env file.
+
+This command starts a throwaway container from the image, runs php -m and
+php -i, and compares the loaded modules with the configured extensions plus
+the modules built into the official php images. It also checks that the
+running PHP version matches --php-version when given, or else the
+io.vess.php-version label of images built by vess.
+
+The command fails with a diff if anything is missing, for example a PECL
+extension that was compiled but never enabled.
+
+Use --target dev to also expect the PHP_DEV_EXTENSIONS of the env file.`,
+	Example: `  vess verify --image my-app:latest --env-file app.env
+  vess verify -i my-php:8.2 -e examples/laravel.env -p 8.2
+  vess verify -i my-app:dev -e examples/development.env --target dev`,
+	RunE: runVerify,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().StringVarP(&verifyImageRef, "image", "i", "", "Image to verify (tag or ID)")
+	verifyCmd.Flags().StringVarP(&verifyEnvFile, "env-file", "e", ".env", "Path to env file containing PHP extensions")
+	verifyCmd.Flags().StringVar(&verifyTarget, "target", "", "Target the image was built from (dev, prod)")
+	verifyCmd.MarkFlagRequired("image")
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	log := logger.New(IsVerbose())
+
+	client, err := connectDocker(cmd.Context(), log)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return verifyImage(cmd.Context(), log, client, verifyImageRef, verifyEnvFile, verifyTarget)
+}
+
+// verifyImage verifies imageRef against the extensions configured in envPath for target
+func verifyImage(ctx context.Context, log *logger.Logger, client *docker.Client, imageRef, envPath, target string) error {
+	cfg, err := config.ParseEnvFile(envPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse env file: %w", err)
+	}
+	expected := cfg.TargetExtensions(target)
+
+	log.Info("Verifying image: %s", imageRef)
+	verifier := docker.NewVerifier(client, log)
+	result, err := verifier.Verify(ctx, imageRef, expected, expectedPHPVersion(ctx, log, client, imageRef))
+	if err != nil {
+		return fmt.Errorf("failed to verify image: %w", err)
+	}
+
+	log.Info("PHP version: %s", result.PHPVersion)
+	log.Debug("Loaded modules: %s", strings.Join(result.Loaded, ", "))
+
+	if !result.OK() {
+		for _, line := range result.Diff() {
+			log.Error("%s", line)
+		}
+		return fmt.Errorf("image %s does not match %s", imageRef, envPath)
+	}
+
+	for _, line := range result.Diff() {
+		log.Info("%s", line)
+	}
+	log.Success("All %d configured extensions are loaded", len(expected))
+
+	return nil
+}
+
+// expectedPHPVersion returns the PHP version imageRef is checked against:
+// --php-version when given, else the version vess labelled the image with.
+// Other images are not version checked.
+func expectedPHPVersion(ctx context.Context, log *logger.Logger, client *docker.Client, imageRef string) string {
+	if rootCmd.PersistentFlags().Changed("php-version") {
+		return GetPHPVersion()
+	}
+	meta, err := docker.NewBuilder(client, log).InspectImage(ctx, imageRef)
+	if err != nil {
+		return ""
+	}
+	return meta.Labels[generator.LabelPHPVersion]
+}
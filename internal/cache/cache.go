@@ -0,0 +1,321 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EnvCacheDir overrides the default cache directory
+const EnvCacheDir = "VESS_CACHE_DIR"
+
+// metaFile is the name of the entry description inside an entry directory
+const metaFile = "meta.json"
+
+// ArchFile records the architecture of the modules staged by Export
+const ArchFile = "arch"
+
+// Entry is a pre-built extension stored in the cache
+type Entry struct {
+	Extension    string    `json:"extension"`
+	Version      string    `json:"version"`
+	PHPVersion   string    `json:"php_version"`
+	OSType       string    `json:"os"`
+	Arch         string    `json:"arch"`
+	ExtensionDir string    `json:"extension_dir"`      // PHP extension_dir the module was built for
+	ModuleFile   string    `json:"module_file"`        // e.g. imagick.so
+	IniFile      string    `json:"ini_file,omitempty"` // e.g. docker-php-ext-imagick.ini
+	Size         int64     `json:"size"`
+	Created      time.Time `json:"created"`
+
+	dir string
+}
+
+// Key returns the cache key of the entry
+func (e *Entry) Key() string {
+	return Key(e.Extension, e.Version, e.PHPVersion, e.OSType, e.Arch)
+}
+
+// Dir returns the directory holding the entry files
+func (e *Entry) Dir() string {
+	return e.dir
+}
+
+// Key builds a cache key, e.g. imagick-3.7.0-php8.3-alpine-amd64
+func Key(extension, version, phpVersion, osType, arch string) string {
+	return fmt.Sprintf("%s-%s-php%s-%s-%s", extension, version, phpVersion, osType, arch)
+}
+
+// Cache is a directory of pre-built extensions, one subdirectory per key
+type Cache struct {
+	dir string
+}
+
+// New creates a cache rooted at dir
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// DefaultDir returns $VESS_CACHE_DIR or the vess directory in the user cache dir
+func DefaultDir() string {
+	if dir := os.Getenv(EnvCacheDir); dir != "" {
+		return dir
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "vess", "extensions")
+	}
+	return filepath.Join(os.TempDir(), "vess", "extensions")
+}
+
+// Dir returns the cache root
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// List returns all entries sorted by key. A missing cache directory is empty.
+func (c *Cache) List() ([]*Entry, error) {
+	dirs, err := os.ReadDir(c.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var entries []*Entry
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		entry, err := readEntry(filepath.Join(c.dir, d.Name()))
+		if err != nil {
+			// Skip partially written or foreign directories
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key() < entries[j].Key()
+	})
+	return entries, nil
+}
+
+// Lookup finds the entry for an extension build. An empty version matches the
+// most recently added version. It returns nil when there is no match.
+func (c *Cache) Lookup(extension, version, phpVersion, osType, arch string) (*Entry, error) {
+	if version != "" {
+		entry, err := readEntry(filepath.Join(c.dir, Key(extension, version, phpVersion, osType, arch)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return entry, nil
+	}
+
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	var found *Entry
+	for _, entry := range entries {
+		if entry.Extension != extension || entry.PHPVersion != phpVersion || entry.OSType != osType || entry.Arch != arch {
+			continue
+		}
+		if found == nil || entry.Created.After(found.Created) {
+			found = entry
+		}
+	}
+	return found, nil
+}
+
+// Add stores an entry with its module and optional ini snippet, replacing any
+// existing entry with the same key
+func (c *Cache) Add(entry *Entry, module, ini []byte) error {
+	if entry.Created.IsZero() {
+		entry.Created = time.Now()
+	}
+	entry.Size = int64(len(module) + len(ini))
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write to a temporary directory first so readers never see partial entries
+	tmp, err := os.MkdirTemp(c.dir, ".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := os.WriteFile(filepath.Join(tmp, entry.ModuleFile), module, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", entry.ModuleFile, err)
+	}
+	if entry.IniFile != "" {
+		if err := os.WriteFile(filepath.Join(tmp, entry.IniFile), ini, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", entry.IniFile, err)
+		}
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, metaFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	dir := filepath.Join(c.dir, entry.Key())
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to replace cache entry: %w", err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+	entry.dir = dir
+	return nil
+}
+
+// Remove deletes an entry from the cache
+func (c *Cache) Remove(entry *Entry) error {
+	if err := os.RemoveAll(filepath.Join(c.dir, entry.Key())); err != nil {
+		return fmt.Errorf("failed to remove cache entry %s: %w", entry.Key(), err)
+	}
+	return nil
+}
+
+// Import adds the entry stored in dir, as written by CopyTo, to the cache
+func (c *Cache) Import(dir string) (*Entry, error) {
+	entry, err := readEntry(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	module, err := os.ReadFile(filepath.Join(dir, entry.ModuleFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", entry.ModuleFile, err)
+	}
+	var ini []byte
+	if entry.IniFile != "" {
+		if ini, err = os.ReadFile(filepath.Join(dir, entry.IniFile)); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.IniFile, err)
+		}
+	}
+
+	if err := c.Add(entry, module, ini); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// CopyTo copies the entry description, module and ini snippet into dir
+func (e *Entry) CopyTo(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	files := []string{metaFile, e.ModuleFile}
+	if e.IniFile != "" {
+		files = append(files, e.IniFile)
+	}
+	for _, name := range files {
+		if err := copyFile(filepath.Join(e.dir, name), filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Export copies the modules of entries into dir/modules and their ini
+// snippets into dir/conf.d, replacing previous contents of dir, and records
+// their architecture in dir/arch. All entries must share the same
+// extension_dir and architecture.
+func Export(entries []*Entry, dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to clean %s: %w", dir, err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	modulesDir := filepath.Join(dir, "modules")
+	confDir := filepath.Join(dir, "conf.d")
+	for _, d := range []string{modulesDir, confDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", d, err)
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.ExtensionDir != entries[0].ExtensionDir {
+			return fmt.Errorf("cached %s was built for %s, not %s", entry.Extension, entry.ExtensionDir, entries[0].ExtensionDir)
+		}
+		if entry.Arch != entries[0].Arch {
+			return fmt.Errorf("cached %s was built for %s, not %s", entry.Extension, entry.Arch, entries[0].Arch)
+		}
+		if err := copyFile(filepath.Join(entry.dir, entry.ModuleFile), filepath.Join(modulesDir, entry.ModuleFile)); err != nil {
+			return err
+		}
+		if entry.IniFile != "" {
+			if err := copyFile(filepath.Join(entry.dir, entry.IniFile), filepath.Join(confDir, entry.IniFile)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ArchFile), []byte(entries[0].Arch+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ArchFile, err)
+	}
+	return nil
+}
+
+// ExportedArch returns the architecture of the modules Export staged in dir,
+// or "" when dir holds no modules
+func ExportedArch(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ArchFile))
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read %s: %w", ArchFile, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "modules")); err == nil {
+		return "", fmt.Errorf("%s does not record the architecture of its modules; generate the Dockerfile again", dir)
+	}
+	return "", nil
+}
+
+// readEntry loads the entry stored in dir
+func readEntry(dir string) (*Entry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, metaFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse cache entry %s: %w", filepath.Base(dir), err)
+	}
+	if entry.Extension == "" || entry.ModuleFile == "" || strings.ContainsAny(entry.ModuleFile+entry.IniFile, `/\`) {
+		return nil, fmt.Errorf("invalid cache entry %s", filepath.Base(dir))
+	}
+	entry.dir = dir
+	return &entry, nil
+}
+
+// copyFile copies src to dst
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return nil
+}
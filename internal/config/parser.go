@@ -4,22 +4,26 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"vess/internal/extensions"
 )
 
 // ParseEnvFile parses an env file and returns the configuration
-func ParseEnvFile(filepath string) (*extensions.Config, error) {
-	file, err := os.Open(filepath)
+func ParseEnvFile(path string) (*extensions.Config, error) {
+	file, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
 	config := &extensions.Config{
-		Extensions: []string{},
-		Metadata:   make(map[string]string),
+		Source:       path,
+		Extensions:   []string{},
+		PECLVersions: make(map[string]string),
+		Metadata:     make(map[string]string),
 	}
 
 	scanner := bufio.NewScanner(file)
@@ -43,6 +47,32 @@ func ParseEnvFile(filepath string) (*extensions.Config, error) {
 		key := strings.TrimSpace(parts[0])
 		value := strings.TrimSpace(parts[1])
 
+		// Hooks and commands are shell or Dockerfile text whose own quotes must be kept
+		if strings.HasPrefix(key, "HOOK_") {
+			if err := config.Hooks.Add(key, unquote(value)); err != nil {
+				return nil, fmt.Errorf("invalid %s at line %d: %w", key, lineNum, err)
+			}
+			continue
+		}
+		switch key {
+		case "HEALTHCHECK_CMD":
+			config.Healthcheck.Command = unquote(value)
+			continue
+		case "CMD":
+			config.Process.Command = unquote(value)
+			continue
+		case "ENTRYPOINT":
+			config.Process.Entrypoint = unquote(value)
+			continue
+		case "PROCESS":
+			program, err := extensions.ParseProgram(unquote(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid PROCESS at line %d: %w", lineNum, err)
+			}
+			config.Process.Programs = append(config.Process.Programs, program)
+			continue
+		}
+
 		// Remove quotes if present
 		value = strings.Trim(value, `"'`)
 
@@ -50,6 +80,81 @@ func ParseEnvFile(filepath string) (*extensions.Config, error) {
 		if key == "PHP_EXTENSIONS" {
 			exts := parseExtensions(value)
 			config.Extensions = append(config.Extensions, exts...)
+		} else if key == "PHP_DEV_EXTENSIONS" {
+			config.DevExtensions = append(config.DevExtensions, parseExtensions(value)...)
+		} else if key == "PHP_INI" || key == "PHP_DEV_INI" {
+			setting, err := parseIniSetting(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s at line %d: %w", key, lineNum, err)
+			}
+			if key == "PHP_INI" {
+				config.Ini = append(config.Ini, setting)
+			} else {
+				config.DevIni = append(config.DevIni, setting)
+			}
+		} else if key == "SYSTEM_PACKAGES" {
+			config.SystemPackages = append(config.SystemPackages, parseExtensions(value)...)
+		} else if key == "SYSTEM_BUILD_PACKAGES" {
+			config.SystemBuildPackages = append(config.SystemBuildPackages, parseExtensions(value)...)
+		} else if key == "HEALTHCHECK" {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid HEALTHCHECK at line %d: expected true or false, got %q", lineNum, value)
+			}
+			config.Healthcheck.Enable = &enabled
+		} else if key == "HEALTHCHECK_PATH" {
+			config.Healthcheck.Path = value
+		} else if key == "HEALTHCHECK_INTERVAL" {
+			config.Healthcheck.Interval = value
+		} else if key == "HEALTHCHECK_TIMEOUT" {
+			config.Healthcheck.Timeout = value
+		} else if key == "HEALTHCHECK_START_PERIOD" {
+			config.Healthcheck.StartPeriod = value
+		} else if key == "HEALTHCHECK_RETRIES" {
+			config.Healthcheck.Retries = value
+		} else if key == "PROCESS_MANAGER" {
+			config.Process.Manager = strings.ToLower(value)
+		} else if key == "INIT" {
+			config.Process.Init = strings.ToLower(value)
+		} else if key == "NGINX_DOCUMENT_ROOT" {
+			config.Nginx.DocumentRoot = value
+		} else if key == "NGINX_CLIENT_MAX_BODY_SIZE" {
+			config.Nginx.ClientMaxBodySize = value
+		} else if key == "NGINX_FASTCGI_READ_TIMEOUT" {
+			config.Nginx.FastCGIReadTimeout = value
+		} else if key == "XDEBUG_MODE" {
+			config.Xdebug.Mode = strings.ReplaceAll(value, " ", "")
+		} else if key == "XDEBUG_CLIENT_HOST" {
+			config.Xdebug.ClientHost = value
+		} else if key == "XDEBUG_CLIENT_PORT" {
+			config.Xdebug.ClientPort = value
+		} else if key == "BASE_IMAGE" {
+			config.Base.Image = value
+		} else if key == "BUILDER_IMAGE" {
+			config.Base.BuilderImage = value
+		} else if key == "BASE_REGISTRY" {
+			config.Base.Registry = value
+		} else if key == "BASE_IMAGE_DIGEST" {
+			config.Base.Digest = strings.ToLower(value)
+		} else if key == "BASE_OS" {
+			config.Base.OS = strings.ToLower(value)
+		} else if key == "BASE_INSTALLERS" {
+			// BASE_INSTALLERS=none declares a base without any installer
+			config.Base.Installers = []string{}
+			for _, installer := range parseExtensions(strings.ToLower(value)) {
+				if installer != "none" {
+					config.Base.Installers = append(config.Base.Installers, installer)
+				}
+			}
+		} else if key == "TEMPLATES_DIR" {
+			config.TemplatesDir = value
+			if value != "" && !filepath.IsAbs(value) {
+				config.TemplatesDir = filepath.Join(filepath.Dir(path), value)
+			}
+		} else if key == "PECL_VERSIONS" {
+			if err := parseVersions(value, config.PECLVersions); err != nil {
+				return nil, fmt.Errorf("invalid PECL_VERSIONS at line %d: %w", lineNum, err)
+			}
 		} else {
 			config.Metadata[key] = value
 		}
@@ -66,7 +171,7 @@ func ParseEnvFile(filepath string) (*extensions.Config, error) {
 	return config, nil
 }
 
-// parseExtensions parses comma-separated extension names
+// parseExtensions parses comma-separated extension or package names
 func parseExtensions(value string) []string {
 	exts := strings.Split(value, ",")
 	result := make([]string, 0, len(exts))
@@ -80,3 +185,42 @@ func parseExtensions(value string) []string {
 
 	return result
 }
+
+// unquote removes one pair of matching surrounding quotes
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// parseVersions parses comma-separated name:version pairs into versions
+func parseVersions(value string, versions map[string]string) error {
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, version, ok := strings.Cut(pair, ":")
+		name, version = strings.TrimSpace(name), strings.TrimSpace(version)
+		if !ok || name == "" || version == "" {
+			return fmt.Errorf("expected name:version, got %q", pair)
+		}
+		versions[name] = version
+	}
+	return nil
+}
+
+// parseIniSetting normalizes a single name=value php.ini setting
+func parseIniSetting(value string) (string, error) {
+	name, setting, ok := strings.Cut(value, "=")
+	name, setting = strings.TrimSpace(name), strings.TrimSpace(setting)
+	if !ok || name == "" || strings.ContainsAny(name, " \t;[]") {
+		return "", fmt.Errorf("expected name=value, got %q", value)
+	}
+	if strings.ContainsAny(setting, "\n\r") {
+		return "", fmt.Errorf("setting %s must be on a single line", name)
+	}
+	return name + "=" + setting, nil
+}
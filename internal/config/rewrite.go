@@ -0,0 +1,36 @@
+package config
+
+import (
+	"strings"
+)
+
+// RewriteEnv rewrites the KEY=VALUE lines of env file content, keeping
+// comments, blank lines and the order of the file. rewrite receives each key
+// with its unquoted value and returns the new value and whether to keep the
+// line; lines whose value is unchanged are kept verbatim.
+func RewriteEnv(content string, rewrite func(key, value string) (string, bool)) string {
+	lines := strings.SplitAfter(content, "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		key, value, ok := strings.Cut(trimmed, "=")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || !ok {
+			b.WriteString(line)
+			continue
+		}
+
+		key, value = strings.TrimSpace(key), unquote(strings.TrimSpace(value))
+		updated, keep := rewrite(key, value)
+		switch {
+		case !keep:
+		case updated == value:
+			b.WriteString(line)
+		default:
+			b.WriteString(key + "=" + updated)
+			if strings.HasSuffix(line, "\n") {
+				b.WriteString("\n")
+			}
+		}
+	}
+	return b.String()
+}
@@ -2,7 +2,10 @@ package config
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"vess/internal/extensions"
 )
@@ -21,47 +24,387 @@ func (v *Validator) Validate(cfg *extensions.Config, osType, phpVersion, imageTy
 		return fmt.Errorf("no extensions specified")
 	}
 
-	// Validate OS
-	if osType != "alpine" && osType != "ubuntu" {
-		return fmt.Errorf("unsupported OS: %s (must be 'alpine' or 'ubuntu')", osType)
+	if err := v.CheckCompatibility(osType, phpVersion, imageType); err != nil {
+		return err
 	}
 
-	// Validate PHP version
-	validVersions := []string{"7.4", "8.0", "8.1", "8.2", "8.3"}
-	if !contains(validVersions, phpVersion) {
-		return fmt.Errorf("unsupported PHP version: %s (must be one of: %s)", 
-			phpVersion, strings.Join(validVersions, ", "))
+	// Validate each extension
+	all := cfg.TargetExtensions(extensions.TargetDev)
+	for _, extName := range all {
+		if err := v.ValidateExtension(extName, osType, phpVersion); err != nil {
+			return err
+		}
 	}
 
-	// Validate image type
-	validTypes := []string{"cli", "fpm", "apache"}
-	if !contains(validTypes, imageType) {
-		return fmt.Errorf("unsupported image type: %s (must be one of: %s)", 
-			imageType, strings.Join(validTypes, ", "))
+	// Dev extensions are installed on top of the prod ones
+	for _, extName := range cfg.DevExtensions {
+		if contains(cfg.Extensions, extName) {
+			return &extensions.ValidationError{
+				Extension: extName,
+				Message:   fmt.Sprintf("extension '%s' is listed in both PHP_EXTENSIONS and PHP_DEV_EXTENSIONS", extName),
+			}
+		}
 	}
 
-	// Validate OS + image type compatibility
-	if osType == "alpine" && imageType == "apache" {
-		return fmt.Errorf("apache image type is not available for Alpine Linux (Docker Hub does not provide php:*-apache-alpine images). Please use --os ubuntu instead")
+	// Check for conflicts
+	if err := v.checkConflicts(all); err != nil {
+		return err
 	}
 
-	// Validate each extension
+	if err := v.checkPECLVersions(cfg, osType); err != nil {
+		return err
+	}
+
+	if err := v.checkPackages(cfg, osType); err != nil {
+		return err
+	}
+
+	if err := v.checkRequiredExtensions(cfg, imageType); err != nil {
+		return err
+	}
+
+	if err := v.checkHealthcheck(cfg, imageType); err != nil {
+		return err
+	}
+
+	if err := v.checkProcess(cfg, imageType); err != nil {
+		return err
+	}
+
+	if err := v.checkNginx(cfg); err != nil {
+		return err
+	}
+
+	if err := v.checkBaseImage(cfg, osType); err != nil {
+		return err
+	}
+
+	return v.checkXdebug(cfg)
+}
+
+// devOnlyExtensions slow down or expose production images and belong in PHP_DEV_EXTENSIONS
+var devOnlyExtensions = []string{"xdebug", "pcov"}
+
+// Warnings returns non-fatal problems with the configuration
+func (v *Validator) Warnings(cfg *extensions.Config) []string {
+	var warnings []string
 	for _, extName := range cfg.Extensions {
-		if err := v.validateExtension(extName, osType, phpVersion); err != nil {
-			return err
+		if contains(devOnlyExtensions, extName) {
+			warnings = append(warnings, fmt.Sprintf("%s is installed in the prod target; move it to PHP_DEV_EXTENSIONS", extName))
 		}
 	}
 
-	// Check for conflicts
-	if err := v.checkConflicts(cfg.Extensions); err != nil {
+	if cfg.Base.Image != "" && cfg.Base.OS == "" {
+		warnings = append(warnings, "BASE_IMAGE is set without BASE_OS; its packages are resolved for --os")
+	}
+
+	if !contains(cfg.TargetExtensions(extensions.TargetDev), "xdebug") {
+		if cfg.Xdebug.IsSet() {
+			warnings = append(warnings, "XDEBUG_* settings are ignored because xdebug is not installed")
+		}
+		return warnings
+	}
+
+	// Xdebug overrides zend_execute_ex, which makes PHP disable the JIT at startup
+	ini := cfg.Ini
+	if contains(cfg.DevExtensions, "xdebug") {
+		ini = append(append([]string{}, cfg.Ini...), cfg.DevIni...)
+	}
+	if jitEnabled(ini) {
+		warnings = append(warnings, "xdebug is incompatible with the opcache JIT; PHP disables the JIT in images with xdebug loaded")
+	}
+	return warnings
+}
+
+// jitEnabled reports whether php.ini settings turn on the opcache JIT
+func jitEnabled(settings []string) bool {
+	values := make(map[string]string)
+	for _, setting := range settings {
+		name, value, _ := strings.Cut(setting, "=")
+		values[strings.ToLower(name)] = strings.ToLower(strings.Trim(value, `"'`))
+	}
+
+	jit, hasJIT := values["opcache.jit"]
+	if hasJIT && (jit == "off" || jit == "disable" || jit == "0") {
+		return false
+	}
+	buffer := values["opcache.jit_buffer_size"]
+	return hasJIT || (buffer != "" && buffer != "0")
+}
+
+// checkPackages checks the system package names against the rules of osType
+func (v *Validator) checkPackages(cfg *extensions.Config, osType string) error {
+	if err := checkPackageNames("SYSTEM_PACKAGES", cfg.SystemPackages, osType); err != nil {
+		return err
+	}
+	return checkPackageNames("SYSTEM_BUILD_PACKAGES", cfg.SystemBuildPackages, osType)
+}
+
+// checkPackageNames validates the packages of a config key after mapping them to osType
+func checkPackageNames(key string, pkgs []string, osType string) error {
+	for _, pkg := range extensions.ResolvePackages(pkgs, osType) {
+		if err := extensions.ValidatePackageName(pkg, osType); err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// checkRequiredExtensions checks that the extensions imageType depends on are installed
+func (v *Validator) checkRequiredExtensions(cfg *extensions.Config, imageType string) error {
+	typ, _ := extensions.GetImageType(imageType)
+	for _, extName := range typ.RequiredExtensions {
+		if !contains(cfg.Extensions, extName) {
+			return &extensions.ValidationError{
+				Extension: extName,
+				Message:   fmt.Sprintf("%s images require the %s extension; add it to PHP_EXTENSIONS", imageType, extName),
+			}
+		}
+	}
+	return nil
+}
+
+// checkHealthcheck validates the HEALTHCHECK_* settings for imageType
+func (v *Validator) checkHealthcheck(cfg *extensions.Config, imageType string) error {
+	hc := cfg.Healthcheck
+	if !hc.IsEnabled() {
+		return nil
+	}
+
+	if hc.Command == "" && !extensions.HasDefaultHealthcheck(imageType) {
+		return fmt.Errorf("HEALTHCHECK_CMD is required to health check %s images", imageType)
+	}
+	durations := []struct{ key, value string }{
+		{"HEALTHCHECK_INTERVAL", hc.Interval},
+		{"HEALTHCHECK_TIMEOUT", hc.Timeout},
+		{"HEALTHCHECK_START_PERIOD", hc.StartPeriod},
+	}
+	for _, duration := range durations {
+		if duration.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(duration.value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid %s '%s' (expected a duration such as 30s)", duration.key, duration.value)
+		}
+	}
+	if hc.Retries != "" {
+		if retries, err := strconv.Atoi(hc.Retries); err != nil || retries < 1 {
+			return fmt.Errorf("invalid HEALTHCHECK_RETRIES '%s'", hc.Retries)
+		}
+	}
+	if hc.Path != "" && (!strings.HasPrefix(hc.Path, "/") || strings.ContainsAny(hc.Path, " \t\"'")) {
+		return fmt.Errorf("invalid HEALTHCHECK_PATH '%s'", hc.Path)
+	}
+	return nil
+}
+
+// checkProcess validates the CMD, ENTRYPOINT, PROCESS_MANAGER, PROCESS and INIT settings
+func (v *Validator) checkProcess(cfg *extensions.Config, imageType string) error {
+	proc := cfg.Process
+	if _, err := extensions.SplitCommand(proc.Command); err != nil {
+		return fmt.Errorf("invalid CMD: %w", err)
+	}
+	if _, err := extensions.SplitCommand(proc.Entrypoint); err != nil {
+		return fmt.Errorf("invalid ENTRYPOINT: %w", err)
+	}
+
+	switch proc.Init {
+	case "", "none", extensions.InitTini:
+	default:
+		return fmt.Errorf("unsupported INIT: %s (must be one of: none, %s)", proc.Init, extensions.InitTini)
+	}
+
+	typ, _ := extensions.GetImageType(imageType)
+	manager := proc.Manager
+	if manager == "" && typ.RequiresProcessManager {
+		manager = extensions.ProcessManagerSupervisord
+	}
+	switch manager {
+	case "", "none":
+		if typ.RequiresProcessManager {
+			return fmt.Errorf("%s images run several processes and need a PROCESS_MANAGER (%s or %s)",
+				imageType, extensions.ProcessManagerSupervisord, extensions.ProcessManagerS6)
+		}
+		if len(proc.Programs) > 0 {
+			return fmt.Errorf("PROCESS requires PROCESS_MANAGER (%s or %s)", extensions.ProcessManagerSupervisord, extensions.ProcessManagerS6)
+		}
+		return nil
+	case extensions.ProcessManagerSupervisord, extensions.ProcessManagerS6:
+	default:
+		return fmt.Errorf("unsupported PROCESS_MANAGER: %s (must be one of: none, %s, %s)",
+			manager, extensions.ProcessManagerSupervisord, extensions.ProcessManagerS6)
+	}
+
+	if manager == extensions.ProcessManagerS6 {
+		// s6-overlay's /init must be the entrypoint and already handles signals
+		if proc.Init == extensions.InitTini {
+			return fmt.Errorf("INIT=%s cannot be combined with PROCESS_MANAGER=%s, which is an init itself", proc.Init, manager)
+		}
+		if proc.Entrypoint != "" {
+			return fmt.Errorf("ENTRYPOINT cannot be set with PROCESS_MANAGER=%s", manager)
+		}
+	}
+
+	if proc.Command == "" && len(typ.Programs) == 0 && len(proc.Programs) == 0 {
+		return fmt.Errorf("PROCESS_MANAGER=%s needs CMD or PROCESS entries for %s images", manager, imageType)
+	}
+	seen := make(map[string]bool)
+	for _, program := range proc.Programs {
+		if seen[program.Name] {
+			return fmt.Errorf("duplicate PROCESS name '%s'", program.Name)
+		}
+		seen[program.Name] = true
+	}
+	return nil
+}
+
+// nginx size and time values, e.g. 8m or 60s
+var (
+	nginxSizePattern = regexp.MustCompile(`^[0-9]+[kKmMgG]?$`)
+	nginxTimePattern = regexp.MustCompile(`^[0-9]+(ms|s|m|h|d)?$`)
+)
+
+// checkNginx validates the NGINX_* settings
+func (v *Validator) checkNginx(cfg *extensions.Config) error {
+	nginx := cfg.Nginx
+	if nginx.DocumentRoot != "" && (!strings.HasPrefix(nginx.DocumentRoot, "/") || strings.ContainsAny(nginx.DocumentRoot, " \t;{}'\"")) {
+		return fmt.Errorf("invalid NGINX_DOCUMENT_ROOT '%s' (must be an absolute path)", nginx.DocumentRoot)
+	}
+	if nginx.ClientMaxBodySize != "" && !nginxSizePattern.MatchString(nginx.ClientMaxBodySize) {
+		return fmt.Errorf("invalid NGINX_CLIENT_MAX_BODY_SIZE '%s' (e.g. 8m, 1g or 0 for no limit)", nginx.ClientMaxBodySize)
+	}
+	if nginx.FastCGIReadTimeout != "" && !nginxTimePattern.MatchString(nginx.FastCGIReadTimeout) {
+		return fmt.Errorf("invalid NGINX_FASTCGI_READ_TIMEOUT '%s' (e.g. 60s or 5m)", nginx.FastCGIReadTimeout)
+	}
+	return nil
+}
+
+// digestPattern matches a base image digest
+var digestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// checkBaseImage validates the BASE_*, BUILDER_IMAGE settings for osType
+func (v *Validator) checkBaseImage(cfg *extensions.Config, osType string) error {
+	base := cfg.Base
+	for _, ref := range []struct{ key, value string }{
+		{"BASE_IMAGE", base.Image},
+		{"BUILDER_IMAGE", base.BuilderImage},
+		{"BASE_REGISTRY", base.Registry},
+	} {
+		if strings.ContainsAny(ref.value, " \t\"'") || strings.Contains(ref.value, "://") {
+			return fmt.Errorf("invalid %s '%s'", ref.key, ref.value)
+		}
+	}
+
+	if base.Digest != "" {
+		if !digestPattern.MatchString(base.Digest) {
+			return fmt.Errorf("invalid BASE_IMAGE_DIGEST '%s' (must be sha256:<64 hex digits>)", base.Digest)
+		}
+		if strings.Contains(base.Image, "@") {
+			return fmt.Errorf("BASE_IMAGE is already pinned to a digest; remove BASE_IMAGE_DIGEST")
+		}
+	}
+
+	if base.OS != "" {
+		family, ok := extensions.OSTypeOfFamily(base.OS)
+		if !ok {
+			return fmt.Errorf("unsupported BASE_OS: %s (must be one of: %s)", base.OS, strings.Join(extensions.GetOSFamilies(), ", "))
+		}
+		if family != osType {
+			return fmt.Errorf("BASE_OS=%s does not match --os %s; generate with --os %s", base.OS, osType, family)
+		}
+	}
+
+	for _, installer := range base.Installers {
+		if !contains(extensions.GetInstallers(), installer) {
+			return fmt.Errorf("unsupported BASE_INSTALLERS entry: %s (must be one of: none, %s)", installer, strings.Join(extensions.GetInstallers(), ", "))
+		}
+		if (installer == extensions.InstallerAPK || installer == extensions.InstallerAPT) && installer != extensions.GetPackageManager(osType) {
+			return fmt.Errorf("BASE_INSTALLERS lists %s, which is not the package manager of %s", installer, osType)
+		}
+	}
+	return nil
+}
+
+// checkXdebug validates the XDEBUG_* settings
+func (v *Validator) checkXdebug(cfg *extensions.Config) error {
+	if cfg.Xdebug.Mode != "" {
+		if invalid := extensions.InvalidXdebugModes(cfg.Xdebug.Mode); len(invalid) > 0 {
+			return &extensions.ValidationError{
+				Extension: "xdebug",
+				Message: fmt.Sprintf("invalid XDEBUG_MODE '%s' (valid modes: %s)",
+					strings.Join(invalid, ","), strings.Join(extensions.GetXdebugModes(), ", ")),
+			}
+		}
+	}
+	if cfg.Xdebug.ClientPort != "" {
+		if port, err := strconv.Atoi(cfg.Xdebug.ClientPort); err != nil || port < 1 || port > 65535 {
+			return &extensions.ValidationError{
+				Extension: "xdebug",
+				Message:   fmt.Sprintf("invalid XDEBUG_CLIENT_PORT '%s'", cfg.Xdebug.ClientPort),
+			}
+		}
+	}
+	if strings.ContainsAny(cfg.Xdebug.ClientHost, " \t\"'") {
+		return &extensions.ValidationError{
+			Extension: "xdebug",
+			Message:   fmt.Sprintf("invalid XDEBUG_CLIENT_HOST '%s'", cfg.Xdebug.ClientHost),
+		}
+	}
+	return nil
+}
+
+// osNames are the display names of the supported OS types
+var osNames = map[string]string{
+	"alpine": "Alpine Linux",
+	"ubuntu": "Ubuntu",
+}
+
+// CheckCompatibility validates an OS, PHP version and image type combination
+func (v *Validator) CheckCompatibility(osType, phpVersion, imageType string) error {
+	if err := v.CheckTarget(osType, phpVersion, imageType); err != nil {
 		return err
 	}
 
+	// Validate OS + image type compatibility
+	typ, _ := extensions.GetImageType(imageType)
+	if !typ.SupportsOS(osType) {
+		return fmt.Errorf("%s image type is not available for %s (Docker Hub does not provide %s images). Please use --os %s instead",
+			imageType, osNames[osType], typ.BaseImage(osType, "*"), strings.Join(typ.OSTypes, " or --os "))
+	}
+	if !typ.SupportsPHPVersion(phpVersion) {
+		return fmt.Errorf("%s image type is not available for PHP %s (supported: %s)",
+			imageType, phpVersion, strings.Join(typ.PHPVersions, ", "))
+	}
+
 	return nil
 }
 
-// validateExtension validates a single extension
-func (v *Validator) validateExtension(extName, osType, phpVersion string) error {
+// CheckTarget checks that an OS, PHP version and image type are each
+// supported, without checking that they are available together
+func (v *Validator) CheckTarget(osType, phpVersion, imageType string) error {
+	// Validate OS
+	if osType != "alpine" && osType != "ubuntu" {
+		return fmt.Errorf("unsupported OS: %s (must be 'alpine' or 'ubuntu')", osType)
+	}
+
+	// Validate PHP version
+	validVersions := []string{"7.4", "8.0", "8.1", "8.2", "8.3"}
+	if !contains(validVersions, phpVersion) {
+		return fmt.Errorf("unsupported PHP version: %s (must be one of: %s)",
+			phpVersion, strings.Join(validVersions, ", "))
+	}
+
+	// Validate image type
+	if _, ok := extensions.GetImageType(imageType); !ok {
+		return fmt.Errorf("unsupported image type: %s (must be one of: %s)",
+			imageType, strings.Join(extensions.GetImageTypeNames(), ", "))
+	}
+
+	return nil
+}
+
+// ValidateExtension validates a single extension for an OS and PHP version
+func (v *Validator) ValidateExtension(extName, osType, phpVersion string) error {
 	ext, exists := extensions.GetExtension(extName)
 	if !exists {
 		return &extensions.ValidationError{
@@ -90,6 +433,32 @@ func (v *Validator) validateExtension(extName, osType, phpVersion string) error
 	return nil
 }
 
+// ValidatePlatforms checks that the base image is published for each requested
+// platform. It returns the normalized platforms.
+func (v *Validator) ValidatePlatforms(osType string, platforms []string) ([]string, error) {
+	normalized := make([]string, 0, len(platforms))
+	basePlatforms := extensions.GetBasePlatforms(osType)
+
+	for _, platform := range platforms {
+		p, err := extensions.NormalizePlatform(platform)
+		if err != nil {
+			return nil, err
+		}
+		if contains(normalized, p) {
+			continue
+		}
+
+		if !contains(basePlatforms, p) {
+			return nil, fmt.Errorf("platform %s is not available for %s base images (available: %s)",
+				p, osType, strings.Join(basePlatforms, ", "))
+		}
+
+		normalized = append(normalized, p)
+	}
+
+	return normalized, nil
+}
+
 // checkConflicts checks for conflicting extensions
 func (v *Validator) checkConflicts(extNames []string) error {
 	for _, extName := range extNames {
@@ -107,6 +476,27 @@ func (v *Validator) checkConflicts(extNames []string) error {
 	return nil
 }
 
+// checkPECLVersions checks that pinned versions refer to configured PECL extensions
+func (v *Validator) checkPECLVersions(cfg *extensions.Config, osType string) error {
+	for extName := range cfg.PECLVersions {
+		if !contains(cfg.TargetExtensions(extensions.TargetDev), extName) {
+			return &extensions.ValidationError{
+				Extension: extName,
+				Message:   fmt.Sprintf("PECL_VERSIONS pins '%s', which is not in PHP_EXTENSIONS or PHP_DEV_EXTENSIONS", extName),
+			}
+		}
+
+		ext, _ := extensions.GetExtension(extName)
+		if support := ext.OSSupport[osType]; support == nil || !support.PECLInstall {
+			return &extensions.ValidationError{
+				Extension: extName,
+				Message:   fmt.Sprintf("PECL_VERSIONS pins '%s', which is bundled with PHP and has no PECL version", extName),
+			}
+		}
+	}
+	return nil
+}
+
 // contains checks if a slice contains a string
 func contains(slice []string, str string) bool {
 	for _, item := range slice {
@@ -0,0 +1,300 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"vess/internal/config"
+	"vess/internal/extensions"
+	"vess/internal/generator"
+)
+
+// Target is one side of a comparison: a config rendered for an OS, PHP
+// version and image type
+type Target struct {
+	Config     *extensions.Config `json:"-"`
+	OSType     string             `json:"os"`
+	PHPVersion string             `json:"php_version"`
+	ImageType  string             `json:"image_type"`
+}
+
+// Name describes the target, e.g. app.env (8.3-fpm-alpine)
+func (t *Target) Name() string {
+	tag := fmt.Sprintf("%s-%s", t.PHPVersion, t.ImageType)
+	if t.OSType == "alpine" {
+		tag += "-alpine"
+	}
+	return fmt.Sprintf("%s (%s)", t.Config.Source, tag)
+}
+
+// MarshalJSON includes the path of the target's config
+func (t *Target) MarshalJSON() ([]byte, error) {
+	type target Target
+	return json.Marshal(struct {
+		Config string `json:"config"`
+		*target
+	}{t.Config.Source, (*target)(t)})
+}
+
+// Model is the resolved view of a target that comparisons are made on
+type Model struct {
+	BaseImage     string            `json:"base_image"`
+	BuilderImage  string            `json:"builder_image"`
+	Extensions    []string          `json:"extensions"`
+	DevExtensions []string          `json:"dev_extensions,omitempty"`
+	PECLVersions  map[string]string `json:"pecl_versions,omitempty"`
+	// OS packages of the prod and dev targets
+	BuildDeps   []string `json:"build_deps"`
+	RuntimeDeps []string `json:"runtime_deps"`
+	// Port exposed by the image type; empty for cli
+	Port string `json:"port,omitempty"`
+	// Process holds the entrypoint, command, process manager and its
+	// programs, e.g. "cmd: php-fpm" or "program horizon: php artisan horizon"
+	Process []string `json:"process,omitempty"`
+	// RoadRunner is the rr release installed in roadrunner images
+	RoadRunner string `json:"roadrunner,omitempty"`
+	// Nginx holds the server block of nginx-fpm images
+	Nginx []string `json:"nginx,omitempty"`
+	// Healthcheck is the HEALTHCHECK command and its timings
+	Healthcheck string `json:"healthcheck,omitempty"`
+	// Conflicting extension pairs, e.g. "apcu <-> apcu_bc"
+	Conflicts []string `json:"conflicts,omitempty"`
+	// Problems the validator reports for the target, such as extensions
+	// that do not support its PHP version
+	Issues []string `json:"issues,omitempty"`
+}
+
+// Build resolves the model of t
+func Build(t *Target) *Model {
+	cfg := t.Config
+	all := cfg.TargetExtensions(extensions.TargetDev)
+	m := &Model{
+		Extensions:    sorted(cfg.Extensions),
+		DevExtensions: sorted(cfg.DevExtensions),
+		PECLVersions:  cfg.PECLVersions,
+		Conflicts:     conflicts(all),
+	}
+
+	validator := config.NewValidator()
+	if err := validator.CheckCompatibility(t.OSType, t.PHPVersion, t.ImageType); err != nil {
+		m.Issues = append(m.Issues, err.Error())
+		return m
+	}
+	// Validate stops at the first problem, so report every unsupported
+	// extension first
+	for _, extName := range all {
+		if err := validator.ValidateExtension(extName, t.OSType, t.PHPVersion); err != nil {
+			m.Issues = append(m.Issues, err.Error())
+		}
+	}
+	if err := validator.Validate(cfg, t.OSType, t.PHPVersion, t.ImageType); err != nil && !contains(m.Issues, err.Error()) {
+		m.Issues = append(m.Issues, err.Error())
+	}
+
+	data, err := generator.PrepareTemplateData(t.OSType, t.PHPVersion, t.ImageType, cfg)
+	if err != nil {
+		m.Issues = append(m.Issues, err.Error())
+		return m
+	}
+	m.BaseImage, m.BuilderImage = data.BaseImage, data.BuilderImage
+	m.BuildDeps, m.RuntimeDeps = data.BuildDeps, data.RuntimeDeps
+	if data.Dev != nil {
+		m.BuildDeps = sorted(append(append([]string{}, m.BuildDeps...), data.Dev.BuildDeps...))
+		m.RuntimeDeps = sorted(append(append([]string{}, m.RuntimeDeps...), data.Dev.RuntimeDeps...))
+	}
+	m.Port = data.Port
+	m.Process = process(data.Process)
+	if data.RoadRunner != nil {
+		m.RoadRunner = data.RoadRunner.Version
+	}
+	if data.Nginx != nil {
+		m.Nginx = data.Nginx.Conf
+	}
+	if hc := data.Healthcheck; hc != nil {
+		m.Healthcheck = fmt.Sprintf("%s (interval %s, timeout %s, start period %s, retries %s)",
+			hc.Command, hc.Interval, hc.Timeout, hc.StartPeriod, hc.Retries)
+	}
+	return m
+}
+
+// process describes how the container starts, one entry per setting
+func process(p *generator.ProcessData) []string {
+	if p == nil {
+		return nil
+	}
+	var entries []string
+	if p.Entrypoint != nil {
+		entries = append(entries, "entrypoint: "+strings.Join(p.Entrypoint, " "))
+	}
+	if p.Cmd != nil {
+		entries = append(entries, "cmd: "+strings.Join(p.Cmd, " "))
+	}
+	if p.Manager != "" {
+		entries = append(entries, "manager: "+p.Manager)
+	}
+	for _, program := range p.Programs {
+		entries = append(entries, fmt.Sprintf("program %s: %s", program.Name, program.Command))
+	}
+	return entries
+}
+
+// conflicts returns the conflicting pairs among extNames
+func conflicts(extNames []string) []string {
+	var pairs []string
+	for _, extName := range extNames {
+		ext, ok := extensions.GetExtension(extName)
+		if !ok {
+			continue
+		}
+		for _, conflict := range ext.Conflicts {
+			if !contains(extNames, conflict) {
+				continue
+			}
+			a, b := extName, conflict
+			if b < a {
+				a, b = b, a
+			}
+			if pair := a + " <-> " + b; !contains(pairs, pair) {
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// Change is a value that differs between the targets
+type Change struct {
+	Name string `json:"name,omitempty"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ListChange holds the entries of a list added or removed by the second target
+type ListChange struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Result is the difference between two targets
+type Result struct {
+	From *Target `json:"from"`
+	To   *Target `json:"to"`
+
+	BaseImage     *Change     `json:"base_image,omitempty"`
+	BuilderImage  *Change     `json:"builder_image,omitempty"`
+	Extensions    *ListChange `json:"extensions,omitempty"`
+	DevExtensions *ListChange `json:"dev_extensions,omitempty"`
+	PECLVersions  []*Change   `json:"pecl_versions,omitempty"`
+	BuildDeps     *ListChange `json:"build_deps,omitempty"`
+	RuntimeDeps   *ListChange `json:"runtime_deps,omitempty"`
+	Port          *Change     `json:"port,omitempty"`
+	Process       *ListChange `json:"process,omitempty"`
+	RoadRunner    *Change     `json:"roadrunner,omitempty"`
+	Nginx         *ListChange `json:"nginx,omitempty"`
+	Healthcheck   *Change     `json:"healthcheck,omitempty"`
+	Conflicts     *ListChange `json:"conflicts,omitempty"`
+	Issues        *ListChange `json:"issues,omitempty"`
+}
+
+// Compare returns what changes between the models of from and to
+func Compare(from, to *Target) *Result {
+	a, b := Build(from), Build(to)
+	r := &Result{
+		From:          from,
+		To:            to,
+		BaseImage:     compareValue("", a.BaseImage, b.BaseImage),
+		BuilderImage:  compareValue("", a.BuilderImage, b.BuilderImage),
+		Extensions:    compareList(a.Extensions, b.Extensions),
+		DevExtensions: compareList(a.DevExtensions, b.DevExtensions),
+		BuildDeps:     compareList(a.BuildDeps, b.BuildDeps),
+		RuntimeDeps:   compareList(a.RuntimeDeps, b.RuntimeDeps),
+		Port:          compareValue("", a.Port, b.Port),
+		Process:       compareList(a.Process, b.Process),
+		RoadRunner:    compareValue("", a.RoadRunner, b.RoadRunner),
+		Nginx:         compareList(a.Nginx, b.Nginx),
+		Healthcheck:   compareValue("", a.Healthcheck, b.Healthcheck),
+		Conflicts:     compareList(a.Conflicts, b.Conflicts),
+		Issues:        compareList(a.Issues, b.Issues),
+	}
+	// The builder only differs from the base image for custom base images
+	if r.BuilderImage != nil && r.BaseImage != nil && a.BuilderImage == a.BaseImage && b.BuilderImage == b.BaseImage {
+		r.BuilderImage = nil
+	}
+
+	var names []string
+	for name := range a.PECLVersions {
+		names = append(names, name)
+	}
+	for name := range b.PECLVersions {
+		if _, ok := a.PECLVersions[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if change := compareValue(name, a.PECLVersions[name], b.PECLVersions[name]); change != nil {
+			r.PECLVersions = append(r.PECLVersions, change)
+		}
+	}
+	return r
+}
+
+// Empty reports whether the targets have the same model
+func (r *Result) Empty() bool {
+	return r.BaseImage == nil && r.BuilderImage == nil && r.Extensions == nil && r.DevExtensions == nil &&
+		len(r.PECLVersions) == 0 && r.BuildDeps == nil && r.RuntimeDeps == nil && r.Port == nil && r.Process == nil &&
+		r.RoadRunner == nil && r.Nginx == nil && r.Healthcheck == nil && r.Conflicts == nil && r.Issues == nil
+}
+
+// compareValue returns the change from a to b, or nil if they are equal
+func compareValue(name, a, b string) *Change {
+	if a == b {
+		return nil
+	}
+	return &Change{Name: name, From: a, To: b}
+}
+
+// compareList returns the entries b adds to and removes from a, or nil if
+// the lists hold the same entries
+func compareList(a, b []string) *ListChange {
+	change := &ListChange{}
+	for _, entry := range b {
+		if !contains(a, entry) {
+			change.Added = append(change.Added, entry)
+		}
+	}
+	for _, entry := range a {
+		if !contains(b, entry) {
+			change.Removed = append(change.Removed, entry)
+		}
+	}
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		return nil
+	}
+	return change
+}
+
+// sorted returns a sorted copy of list without duplicates
+func sorted(list []string) []string {
+	result := make([]string, 0, len(list))
+	for _, entry := range list {
+		if !contains(result, entry) {
+			result = append(result, entry)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// contains checks if a slice contains a string
+func contains(slice []string, str string) bool {
+	for _, s := range slice {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
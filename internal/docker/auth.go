@@ -0,0 +1,189 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+)
+
+// Environment variables that override credentials from the Docker config
+const (
+	EnvRegistryUsername = "VESS_REGISTRY_USERNAME"
+	EnvRegistryPassword = "VESS_REGISTRY_PASSWORD"
+)
+
+// dockerHubAuthKey is the key Docker uses for Docker Hub in config.json
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// dockerConfigFile is the subset of ~/.docker/config.json used by vess
+type dockerConfigFile struct {
+	Auths map[string]struct {
+		Auth          string `json:"auth"`
+		Username      string `json:"username"`
+		Password      string `json:"password"`
+		IdentityToken string `json:"identitytoken"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// DockerConfigDir returns the Docker CLI configuration directory
+func DockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".docker"
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// loadDockerConfig reads config.json, returning an empty config if it does not exist
+func loadDockerConfig() (*dockerConfigFile, error) {
+	cfg := &dockerConfigFile{}
+
+	data, err := os.ReadFile(filepath.Join(DockerConfigDir(), "config.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read Docker config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse Docker config: %w", err)
+	}
+	return cfg, nil
+}
+
+// RegistryHost returns the registry host of an image reference
+func RegistryHost(imageRef string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %q: %w", imageRef, err)
+	}
+	return reference.Domain(named), nil
+}
+
+// ResolveAuth resolves credentials for the registry of imageRef.
+// Sources are checked in order: VESS_REGISTRY_USERNAME/VESS_REGISTRY_PASSWORD,
+// a per-registry credential helper, the default credential store, and
+// finally the auths section of the Docker config.
+func ResolveAuth(imageRef string) (registry.AuthConfig, error) {
+	host, err := RegistryHost(imageRef)
+	if err != nil {
+		return registry.AuthConfig{}, err
+	}
+
+	serverAddress := host
+	if host == "docker.io" {
+		serverAddress = dockerHubAuthKey
+	}
+
+	if username := os.Getenv(EnvRegistryUsername); username != "" {
+		return registry.AuthConfig{
+			Username:      username,
+			Password:      os.Getenv(EnvRegistryPassword),
+			ServerAddress: serverAddress,
+		}, nil
+	}
+
+	cfg, err := loadDockerConfig()
+	if err != nil {
+		return registry.AuthConfig{}, err
+	}
+
+	if helper, ok := cfg.CredHelpers[host]; ok {
+		return credentialHelperGet(helper, serverAddress)
+	}
+	if cfg.CredsStore != "" {
+		auth, err := credentialHelperGet(cfg.CredsStore, serverAddress)
+		if err == nil && (auth.Username != "" || auth.IdentityToken != "") {
+			return auth, nil
+		}
+	}
+
+	for key, entry := range cfg.Auths {
+		if normalizeAuthKey(key) != normalizeAuthKey(serverAddress) {
+			continue
+		}
+
+		auth := registry.AuthConfig{
+			Username:      entry.Username,
+			Password:      entry.Password,
+			IdentityToken: entry.IdentityToken,
+			ServerAddress: serverAddress,
+		}
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return registry.AuthConfig{}, fmt.Errorf("invalid auth for %s in Docker config: %w", key, err)
+			}
+			user, pass, _ := strings.Cut(string(decoded), ":")
+			auth.Username, auth.Password = user, pass
+		}
+		return auth, nil
+	}
+
+	// Anonymous access, e.g. a local registry:2 instance
+	return registry.AuthConfig{ServerAddress: serverAddress}, nil
+}
+
+// EncodeAuth resolves and encodes credentials for the X-Registry-Auth header
+func EncodeAuth(imageRef string) (string, error) {
+	auth, err := ResolveAuth(imageRef)
+	if err != nil {
+		return "", err
+	}
+	return registry.EncodeAuthConfig(auth)
+}
+
+// credentialHelperGet asks docker-credential-<helper> for the credentials of serverAddress
+func credentialHelperGet(helper, serverAddress string) (registry.AuthConfig, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverAddress)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return registry.AuthConfig{}, fmt.Errorf("credential helper %s failed: %s", helper, strings.TrimSpace(stdout.String()+stderr.String()))
+	}
+
+	var creds struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+		return registry.AuthConfig{}, fmt.Errorf("invalid output from credential helper %s: %w", helper, err)
+	}
+
+	auth := registry.AuthConfig{ServerAddress: serverAddress}
+	if creds.Username == "<token>" {
+		auth.IdentityToken = creds.Secret
+	} else {
+		auth.Username = creds.Username
+		auth.Password = creds.Secret
+	}
+	return auth, nil
+}
+
+// normalizeAuthKey strips the scheme and path from a config.json auths key
+func normalizeAuthKey(key string) string {
+	key = strings.TrimPrefix(key, "https://")
+	key = strings.TrimPrefix(key, "http://")
+	host, _, _ := strings.Cut(key, "/")
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		return "docker.io"
+	}
+	return host
+}
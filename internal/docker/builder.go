@@ -1,12 +1,14 @@
 package docker
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"os"
+	"time"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 )
 
@@ -23,6 +25,35 @@ type Builder struct {
 	logger Logger
 }
 
+// BuildOptions configures a single image build
+type BuildOptions struct {
+	Dockerfile string
+	Tag        string
+	NoCache    bool
+	Labels     map[string]string
+	// Target selects the stage to build; empty builds the last stage
+	Target string
+	// ContextDir holds files referenced by COPY/ADD; empty sends only the Dockerfile and .vess
+	ContextDir string
+	// Platforms builds for the given platforms; more than one produces an image index
+	Platforms []string
+	// Push pushes multi-platform builds as part of the build
+	Push bool
+	// OCIOutput exports the build as an OCI archive instead of loading it
+	OCIOutput string
+	// Progress receives build events; defaults to plain output on stdout
+	Progress ProgressRenderer
+}
+
+// BuildResult describes a finished build
+type BuildResult struct {
+	ImageID   string        `json:"image_id,omitempty"`
+	Digest    string        `json:"digest,omitempty"`
+	Platforms []string      `json:"platforms,omitempty"`
+	Steps     []StepResult  `json:"steps"`
+	Duration  time.Duration `json:"duration"`
+}
+
 // NewBuilder creates a new Docker builder
 func NewBuilder(client *Client, logger Logger) *Builder {
 	return &Builder{
@@ -31,87 +62,116 @@ func NewBuilder(client *Client, logger Logger) *Builder {
 	}
 }
 
-// Build builds a Docker image from a Dockerfile
-func (b *Builder) Build(dockerfilePath, tag string, noCache bool) error {
+// Build builds a Docker image from a Dockerfile.
+// Cancelling ctx aborts the request, which makes the daemon stop the build.
+func (b *Builder) Build(ctx context.Context, opts BuildOptions) (*BuildResult, error) {
+	started := time.Now()
+
 	// Check if Docker daemon is available
-	if err := b.client.Ping(); err != nil {
-		return err
+	if err := b.client.Ping(ctx); err != nil {
+		return nil, err
 	}
 
 	// Create build context
 	b.logger.Debug("Creating build context...")
-	ctx := NewBuildContext(dockerfilePath)
-	buildContext, err := ctx.CreateTar()
+	buildCtx := NewBuildContext(opts.Dockerfile)
+	if opts.ContextDir != "" {
+		buildCtx.SetContextDir(opts.ContextDir)
+	}
+	buildContext, err := buildCtx.CreateTar()
 	if err != nil {
-		return fmt.Errorf("failed to create build context: %w", err)
+		return nil, fmt.Errorf("failed to create build context: %w", err)
 	}
 
 	// Build options
 	buildOptions := types.ImageBuildOptions{
-		Tags:       []string{tag},
+		Tags:       []string{opts.Tag},
 		Dockerfile: "Dockerfile",
 		Remove:     true,
-		NoCache:    noCache,
+		NoCache:    opts.NoCache,
+		Labels:     opts.Labels,
+		Target:     opts.Target,
+	}
+	if len(opts.Platforms) == 1 {
+		buildOptions.Platform = opts.Platforms[0]
 	}
 
+	renderer := opts.Progress
+	if renderer == nil {
+		renderer = &plainRenderer{out: os.Stdout}
+	}
+	defer renderer.Close()
+
+	buildKit, err := RequiresBuildKit(opts.Dockerfile)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Platforms) > 1 || opts.OCIOutput != "" || buildKit {
+		return b.buildExternal(ctx, opts, buildContext, renderer)
+	}
+	tracker := newStreamTracker(renderer)
+
 	// Build image
 	b.logger.Debug("Starting Docker build...")
-	resp, err := b.client.GetClient().ImageBuild(
-		b.client.GetContext(),
-		buildContext,
-		buildOptions,
-	)
+	resp, err := b.client.GetClient().ImageBuild(ctx, buildContext, buildOptions)
 	if err != nil {
-		return fmt.Errorf("failed to build image: %w", err)
+		if ctx.Err() != nil {
+			return nil, interrupted(ctx, "build", buildPosition(tracker))
+		}
+		return nil, fmt.Errorf("failed to build image: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Stream build output
-	if err := b.streamOutput(resp.Body); err != nil {
-		return fmt.Errorf("build failed: %w", err)
-	}
-
-	return nil
-}
-
-// streamOutput streams Docker build output
-func (b *Builder) streamOutput(reader io.Reader) error {
-	decoder := json.NewDecoder(reader)
-
-	for {
-		var message struct {
-			Stream      string `json:"stream"`
-			Error       string `json:"error"`
-			ErrorDetail struct {
-				Message string `json:"message"`
-			} `json:"errorDetail"`
+	if err := tracker.consume(resp.Body); err != nil {
+		if ctx.Err() != nil {
+			return nil, interrupted(ctx, "build", buildPosition(tracker))
 		}
-
-		if err := decoder.Decode(&message); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return err
+		if step := tracker.currentStep(); step != "" {
+			return nil, fmt.Errorf("build failed at %s: %w", step, err)
 		}
+		return nil, fmt.Errorf("build failed: %w", err)
+	}
 
-		if message.Error != "" {
-			return fmt.Errorf("build error: %s", message.Error)
-		}
+	return &BuildResult{
+		ImageID:   tracker.imageID,
+		Platforms: opts.Platforms,
+		Steps:     tracker.steps,
+		Duration:  time.Since(started),
+	}, nil
+}
 
-		if message.Stream != "" {
-			fmt.Fprint(os.Stdout, message.Stream)
-		}
+// interrupted describes an operation that was stopped by its context.
+// position says where, e.g. "at step 3/7 (RUN ...)"; operations without
+// steps pass "".
+func interrupted(ctx context.Context, operation, position string) error {
+	stopped := "cancelled"
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		stopped = "timed out"
+	}
+	if position == "" {
+		return fmt.Errorf("%s %s: %w", operation, stopped, ctx.Err())
 	}
+	return fmt.Errorf("%s %s %s: %w", operation, stopped, position, ctx.Err())
+}
 
-	return nil
+// buildPosition describes the step a classic build stopped at
+func buildPosition(tracker *streamTracker) string {
+	if step := tracker.currentStep(); step != "" {
+		return "at " + step
+	}
+	return "before the first step"
 }
 
-// ListImages lists Docker images
-func (b *Builder) ListImages() ([]image.Summary, error) {
-	images, err := b.client.GetClient().ImageList(
-		b.client.GetContext(),
-		image.ListOptions{},
-	)
+// ListImages lists Docker images, optionally restricted to those carrying all given labels
+// ("key" or "key=value")
+func (b *Builder) ListImages(ctx context.Context, labels ...string) ([]image.Summary, error) {
+	args := filters.NewArgs()
+	for _, label := range labels {
+		args.Add("label", label)
+	}
+
+	images, err := b.client.GetClient().ImageList(ctx, image.ListOptions{Filters: args})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list images: %w", err)
 	}
@@ -119,12 +179,8 @@ func (b *Builder) ListImages() ([]image.Summary, error) {
 }
 
 // RemoveImage removes a Docker image
-func (b *Builder) RemoveImage(imageID string, force bool) error {
-	_, err := b.client.GetClient().ImageRemove(
-		b.client.GetContext(),
-		imageID,
-		image.RemoveOptions{Force: force},
-	)
+func (b *Builder) RemoveImage(ctx context.Context, imageID string, force bool) error {
+	_, err := b.client.GetClient().ImageRemove(ctx, imageID, image.RemoveOptions{Force: force})
 	if err != nil {
 		return fmt.Errorf("failed to remove image: %w", err)
 	}
@@ -0,0 +1,288 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RequiresBuildKit reports whether a Dockerfile needs the BuildKit frontend,
+// i.e. it declares a # syntax= directive or uses RUN --mount
+func RequiresBuildKit(dockerfilePath string) (bool, error) {
+	content, err := os.ReadFile(dockerfilePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read Dockerfile: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if directive, ok := strings.CutPrefix(line, "#"); ok {
+			if strings.HasPrefix(strings.ToLower(strings.TrimSpace(directive)), "syntax=") {
+				return true, nil
+			}
+			continue
+		}
+		if strings.Contains(line, "--mount=") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// buildExternal builds with `docker buildx build` on Docker engines and
+// `podman build` on Podman engines. It is used for multi-platform builds, OCI
+// exports and Dockerfiles that need BuildKit features the classic build API
+// lacks. Multi-platform images cannot be loaded into the classic image store,
+// so such results must be pushed or exported as an OCI archive.
+func (b *Builder) buildExternal(ctx context.Context, opts BuildOptions, buildContext io.Reader, renderer ProgressRenderer) (*BuildResult, error) {
+	if len(opts.Platforms) > 1 && !opts.Push && opts.OCIOutput == "" {
+		return nil, fmt.Errorf("multi-platform builds must be pushed (--push) or exported (--oci-output)")
+	}
+
+	engine, err := b.client.Engine(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	workDir, err := os.MkdirTemp("", "vess-build-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create build directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	contextDir := filepath.Join(workDir, "context")
+	if err := ExtractTar(buildContext, contextDir); err != nil {
+		return nil, fmt.Errorf("failed to prepare build context: %w", err)
+	}
+
+	started := time.Now()
+	result := &BuildResult{Platforms: opts.Platforms}
+
+	if engine.Flavour == "podman" {
+		err = b.podmanBuild(ctx, opts, contextDir, workDir, renderer, result)
+	} else {
+		err = b.buildxBuild(ctx, opts, contextDir, workDir, renderer, result)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, interrupted(ctx, "build", "")
+		}
+		return nil, err
+	}
+
+	result.Duration = time.Since(started)
+	return result, nil
+}
+
+// buildxBuild runs docker buildx build and records the image ID or index digest
+func (b *Builder) buildxBuild(ctx context.Context, opts BuildOptions, contextDir, workDir string, renderer ProgressRenderer, result *BuildResult) error {
+	metadataFile := filepath.Join(workDir, "metadata.json")
+	args := []string{"buildx", "build",
+		"--tag", opts.Tag,
+		"--progress", "plain",
+		"--metadata-file", metadataFile,
+	}
+	if len(opts.Platforms) > 0 {
+		args = append(args, "--platform", strings.Join(opts.Platforms, ","))
+	}
+	args = append(args, labelArgs(opts.Labels)...)
+	if opts.Target != "" {
+		args = append(args, "--target", opts.Target)
+	}
+	if opts.NoCache {
+		args = append(args, "--no-cache")
+	}
+	if opts.Push {
+		args = append(args, "--push")
+	}
+	if opts.OCIOutput != "" {
+		args = append(args, "--output", "type=oci,dest="+opts.OCIOutput)
+	} else if len(opts.Platforms) <= 1 {
+		args = append(args, "--load")
+	}
+	args = append(args, contextDir)
+
+	if err := b.runExternal(ctx, "docker", args, renderer); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(metadataFile)
+	if err != nil {
+		return fmt.Errorf("failed to read buildx metadata: %w", err)
+	}
+	var metadata struct {
+		ConfigDigest string `json:"containerimage.config.digest"`
+		Digest       string `json:"containerimage.digest"`
+	}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return fmt.Errorf("failed to parse buildx metadata: %w", err)
+	}
+	result.Digest = metadata.Digest
+	if len(opts.Platforms) <= 1 {
+		result.ImageID = metadata.ConfigDigest
+	}
+	return nil
+}
+
+// podmanBuild builds an image, or a manifest list for several platforms, with
+// podman and pushes or exports it when requested
+func (b *Builder) podmanBuild(ctx context.Context, opts BuildOptions, contextDir, workDir string, renderer ProgressRenderer, result *BuildResult) error {
+	multiPlatform := len(opts.Platforms) > 1
+	args := []string{"build"}
+	if len(opts.Platforms) > 0 {
+		args = append(args, "--platform", strings.Join(opts.Platforms, ","))
+	}
+	if multiPlatform {
+		args = append(args, "--manifest", opts.Tag)
+	} else {
+		args = append(args, "--tag", opts.Tag, "--iidfile", filepath.Join(workDir, "iid"))
+	}
+	args = append(args, labelArgs(opts.Labels)...)
+	if opts.Target != "" {
+		args = append(args, "--target", opts.Target)
+	}
+	if opts.NoCache {
+		args = append(args, "--no-cache")
+	}
+	args = append(args, contextDir)
+
+	if err := b.runExternal(ctx, "podman", args, renderer); err != nil {
+		return err
+	}
+	if !multiPlatform {
+		iid, _ := os.ReadFile(filepath.Join(workDir, "iid"))
+		result.ImageID = strings.TrimSpace(string(iid))
+	}
+
+	push := []string{"push"}
+	if multiPlatform {
+		push = []string{"manifest", "push", "--all"}
+	}
+
+	digestFile := filepath.Join(workDir, "digest")
+	if opts.OCIOutput != "" {
+		if err := b.runExternal(ctx, "podman", append(push, opts.Tag, "oci-archive:"+opts.OCIOutput), renderer); err != nil {
+			return err
+		}
+	}
+	if opts.Push {
+		if err := b.runExternal(ctx, "podman", append(push, "--digestfile", digestFile, opts.Tag, "docker://"+opts.Tag), renderer); err != nil {
+			return err
+		}
+	}
+
+	digest, _ := os.ReadFile(digestFile)
+	result.Digest = strings.TrimSpace(string(digest))
+	return nil
+}
+
+// runExternal runs an external builder against the client's endpoint, forwarding its output as log events
+func (b *Builder) runExternal(ctx context.Context, name string, args []string, renderer ProgressRenderer) error {
+	b.logger.Debug("Running %s %s", name, strings.Join(args, " "))
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = os.Environ()
+	if ep := b.client.Endpoint(); ep != nil && !strings.HasPrefix(ep.Source, "context:") {
+		if name == "podman" {
+			cmd.Env = append(cmd.Env, "CONTAINER_HOST="+ep.Host)
+		} else {
+			cmd.Env = append(cmd.Env, "DOCKER_HOST="+ep.Host)
+		}
+	}
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	var tail bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			renderer.Render(&ProgressEvent{Time: time.Now(), Type: EventLog, Message: line})
+			tail.Reset()
+			tail.WriteString(line)
+		}
+	}()
+
+	if err := cmd.Start(); err != nil {
+		pw.Close()
+		<-done
+		if name == "docker" {
+			return fmt.Errorf("failed to run docker buildx (is the Docker CLI with buildx installed?): %w", err)
+		}
+		return fmt.Errorf("failed to run %s: %w", name, err)
+	}
+	err := cmd.Wait()
+	pw.Close()
+	<-done
+
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %s", name, args[0], strings.TrimSpace(tail.String()))
+	}
+	return nil
+}
+
+// labelArgs converts labels into --label flags in a stable order
+func labelArgs(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		args = append(args, "--label", key+"="+labels[key])
+	}
+	return args
+}
+
+// ExtractTar writes a tar stream into dir
+func ExtractTar(reader io.Reader, dir string) error {
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, filepath.Clean("/"+header.Name))
+		if header.Typeflag == tar.TypeDir {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&0777)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(file, tr); err != nil {
+			file.Close()
+			return err
+		}
+		file.Close()
+	}
+}
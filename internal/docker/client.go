@@ -3,26 +3,82 @@ package docker
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"strings"
 
+	"github.com/docker/docker/api/types/versions"
 	"github.com/docker/docker/client"
 )
 
+// MinAPIVersion is the oldest engine API version vess supports
+const MinAPIVersion = "1.40"
+
 // Client wraps the Docker SDK client
 type Client struct {
-	cli *client.Client
-	ctx context.Context
+	cli      *client.Client
+	endpoint *Endpoint
+	engine   *EngineInfo
+}
+
+// EngineInfo describes the container engine behind a client
+type EngineInfo struct {
+	Flavour    string // docker or podman
+	Version    string
+	APIVersion string
+	OS         string
+	Arch       string
+	Rootless   bool
+	Host       string
+	Source     string
 }
 
-// NewClient creates a new Docker client
-func NewClient() (*Client, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+// String formats the engine for log output
+func (e *EngineInfo) String() string {
+	mode := ""
+	if e.Rootless {
+		mode = " rootless"
+	}
+	return fmt.Sprintf("%s %s%s (API %s, %s/%s) at %s [%s]", e.Flavour, e.Version, mode, e.APIVersion, e.OS, e.Arch, e.Host, e.Source)
+}
+
+// NewClient creates a new Docker client. host overrides the endpoint
+// resolved from the environment and Docker contexts (see ResolveEndpoint).
+func NewClient(host string) (*Client, error) {
+	endpoint, err := ResolveEndpoint(host)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []client.Opt{client.WithAPIVersionNegotiation()}
+	if isSSHHost(endpoint.Host) {
+		dialer, err := sshDialer(endpoint.Host)
+		if err != nil {
+			return nil, err
+		}
+		// The host is only used to build request URLs; traffic goes through ssh
+		opts = append(opts, client.WithHost("http://docker.example.com"), client.WithDialContext(dialer))
+	} else {
+		opts = append(opts, client.WithHost(endpoint.Host))
+	}
+	if endpoint.TLSDir != "" {
+		opts = append(opts, client.WithTLSClientConfig(
+			filepath.Join(endpoint.TLSDir, "ca.pem"),
+			filepath.Join(endpoint.TLSDir, "cert.pem"),
+			filepath.Join(endpoint.TLSDir, "key.pem"),
+		))
+	} else if endpoint.Source == "env" {
+		// Honour DOCKER_TLS_VERIFY and DOCKER_CERT_PATH for DOCKER_HOST
+		opts = append([]client.Opt{client.FromEnv}, opts...)
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Docker client: %w", err)
 	}
 
 	return &Client{
-		cli: cli,
-		ctx: context.Background(),
+		cli:      cli,
+		endpoint: endpoint,
 	}, nil
 }
 
@@ -39,16 +95,63 @@ func (c *Client) GetClient() *client.Client {
 	return c.cli
 }
 
-// GetContext returns the context
-func (c *Client) GetContext() context.Context {
-	return c.ctx
+// Endpoint returns the resolved engine endpoint
+func (c *Client) Endpoint() *Endpoint {
+	return c.endpoint
 }
 
-// Ping checks if Docker daemon is available
-func (c *Client) Ping() error {
-	_, err := c.cli.Ping(c.ctx)
+// Ping checks if Docker daemon is available and speaks a supported API version
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.cli.Ping(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to ping Docker daemon at %s: %w", c.endpoint.Host, err)
+	}
+
+	engine, err := c.Engine(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to ping Docker daemon: %w", err)
+		return err
+	}
+	if versions.LessThan(engine.APIVersion, MinAPIVersion) {
+		return fmt.Errorf("%s API version %s is not supported (minimum %s)", engine.Flavour, engine.APIVersion, MinAPIVersion)
 	}
 	return nil
 }
+
+// Engine returns information about the engine, querying it on first use
+func (c *Client) Engine(ctx context.Context) (*EngineInfo, error) {
+	if c.engine != nil {
+		return c.engine, nil
+	}
+
+	version, err := c.cli.ServerVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query engine version: %w", err)
+	}
+
+	engine := &EngineInfo{
+		Flavour:    "docker",
+		Version:    version.Version,
+		APIVersion: version.APIVersion,
+		OS:         version.Os,
+		Arch:       version.Arch,
+		Host:       c.endpoint.Host,
+		Source:     c.endpoint.Source,
+	}
+	for _, component := range version.Components {
+		if strings.Contains(strings.ToLower(component.Name), "podman") {
+			engine.Flavour = "podman"
+			engine.Version = component.Version
+		}
+	}
+
+	if info, err := c.cli.Info(ctx); err == nil {
+		for _, opt := range info.SecurityOptions {
+			if strings.Contains(opt, "name=rootless") {
+				engine.Rootless = true
+			}
+		}
+	}
+
+	c.engine = engine
+	return engine, nil
+}
@@ -0,0 +1,109 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// CommandResult holds the output of a command run in a throwaway container
+type CommandResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int64
+}
+
+// RunCommand runs cmd in a throwaway container created from imageRef.
+// The image entrypoint is bypassed so supervisors and init wrappers do not interfere.
+func (c *Client) RunCommand(ctx context.Context, imageRef string, cmd []string) (*CommandResult, error) {
+	return c.runContainer(ctx, imageRef, cmd, "none")
+}
+
+// runContainer runs cmd in a throwaway container attached to network; an
+// empty network uses the engine default
+func (c *Client) runContainer(ctx context.Context, imageRef string, cmd []string, network string) (*CommandResult, error) {
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("no command specified")
+	}
+
+	created, err := c.cli.ContainerCreate(ctx, &container.Config{
+		Image:      imageRef,
+		Entrypoint: cmd[:1],
+		Cmd:        cmd[1:],
+	}, &container.HostConfig{NetworkMode: container.NetworkMode(network)}, nil, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+	defer func() {
+		// Clean up even if ctx was cancelled
+		_ = c.cli.ContainerRemove(context.WithoutCancel(ctx), created.ID, container.RemoveOptions{Force: true})
+	}()
+
+	if err := c.cli.ContainerStart(ctx, created.ID, container.StartOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to start container: %w", err)
+	}
+
+	result := &CommandResult{}
+	waitC, errC := c.cli.ContainerWait(ctx, created.ID, container.WaitConditionNotRunning)
+	select {
+	case resp := <-waitC:
+		result.ExitCode = resp.StatusCode
+	case err := <-errC:
+		return nil, fmt.Errorf("failed to wait for container: %w", err)
+	}
+
+	logs, err := c.cli.ContainerLogs(ctx, created.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read container output: %w", err)
+	}
+	defer logs.Close()
+
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, logs); err != nil {
+		return nil, fmt.Errorf("failed to read container output: %w", err)
+	}
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+
+	return result, nil
+}
+
+// CopyFromImage returns a tar stream of path inside imageRef. The image is
+// never started; a stopped container is created to read from and removed
+// when the stream is closed.
+func (c *Client) CopyFromImage(ctx context.Context, imageRef, path string) (io.ReadCloser, error) {
+	created, err := c.cli.ContainerCreate(ctx, &container.Config{
+		Image: imageRef,
+		// Images built FROM scratch have no command; one is required to create a container
+		Cmd: []string{"/nonexistent"},
+	}, &container.HostConfig{NetworkMode: "none"}, nil, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+	remove := func() {
+		_ = c.cli.ContainerRemove(context.WithoutCancel(ctx), created.ID, container.RemoveOptions{Force: true})
+	}
+
+	reader, _, err := c.cli.CopyFromContainer(ctx, created.ID, path)
+	if err != nil {
+		remove()
+		return nil, fmt.Errorf("failed to copy %s from %s: %w", path, imageRef, err)
+	}
+	return &removingReader{ReadCloser: reader, remove: remove}, nil
+}
+
+// removingReader removes its container once closed
+type removingReader struct {
+	io.ReadCloser
+	remove func()
+}
+
+func (r *removingReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.remove()
+	return err
+}
@@ -12,6 +12,7 @@ import (
 // BuildContext creates a build context for Docker
 type BuildContext struct {
 	dockerfilePath string
+	contextDir     string
 }
 
 // NewBuildContext creates a new build context
@@ -21,12 +22,37 @@ func NewBuildContext(dockerfilePath string) *BuildContext {
 	}
 }
 
-// CreateTar creates a tar archive containing the Dockerfile
+// SetContextDir adds the files below dir to the build context, so that
+// COPY and ADD instructions can refer to them
+func (bc *BuildContext) SetContextDir(dir string) {
+	bc.contextDir = dir
+}
+
+// CreateTar creates a tar archive containing the Dockerfile, the files of the
+// context directory if set and, if present, the .vess directory next to the Dockerfile
 func (bc *BuildContext) CreateTar() (io.Reader, error) {
 	buf := new(bytes.Buffer)
 	tw := tar.NewWriter(buf)
 	defer tw.Close()
 
+	// The generated Dockerfile and .vess directory take precedence over
+	// files of the same name in the context directory
+	if bc.contextDir != "" {
+		entries, err := os.ReadDir(bc.contextDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read context directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.Name() == "Dockerfile" || entry.Name() == VessDir {
+				continue
+			}
+			path := filepath.Join(bc.contextDir, entry.Name())
+			if err := addDirectory(tw, path, entry.Name()); err != nil {
+				return nil, fmt.Errorf("failed to add %s to build context: %w", path, err)
+			}
+		}
+	}
+
 	// Read Dockerfile
 	dockerfileContent, err := os.ReadFile(bc.dockerfilePath)
 	if err != nil {
@@ -48,16 +74,58 @@ func (bc *BuildContext) CreateTar() (io.Reader, error) {
 		return nil, fmt.Errorf("failed to write to tar: %w", err)
 	}
 
-	// Check if there's a directory with additional files
-	dir := filepath.Dir(bc.dockerfilePath)
-	if dir != "." && dir != "/" {
-		// Add other files from the directory if needed
-		// For now, we just include the Dockerfile
+	// Add files generated by vess next to the Dockerfile (e.g. cached extensions)
+	vessDir := filepath.Join(filepath.Dir(bc.dockerfilePath), VessDir)
+	if info, err := os.Stat(vessDir); err == nil && info.IsDir() {
+		if err := addDirectory(tw, vessDir, VessDir); err != nil {
+			return nil, fmt.Errorf("failed to add %s to build context: %w", VessDir, err)
+		}
 	}
 
 	return buf, nil
 }
 
+// VessDir is the directory next to a Dockerfile holding files generated by vess
+const VessDir = ".vess"
+
+// addDirectory writes dir, or the directories and regular files below it, into tw under prefix.
+// Directories are included so that COPY of an empty directory still works.
+func addDirectory(tw *tar.Writer, dir, prefix string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(prefix, relPath))
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(tw, file)
+		return err
+	})
+}
+
 // CreateContextFromDirectory creates a tar archive from a directory
 func CreateContextFromDirectory(dir string) (io.Reader, error) {
 	buf := new(bytes.Buffer)
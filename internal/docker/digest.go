@@ -0,0 +1,220 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+)
+
+// Digest resolvers
+const (
+	// ResolverRegistry asks the registry API for the digest of a tag
+	ResolverRegistry = "registry"
+	// ResolverDaemon asks the container engine, which uses its own registry
+	// access and falls back to pulled images
+	ResolverDaemon = "daemon"
+)
+
+// manifestMediaTypes are accepted when resolving a tag, so multi-platform
+// images resolve to the digest of their index
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// registryHTTPClient is used for registry API requests
+var registryHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// ResolveDigest returns the digest imageRef's tag currently points to,
+// asking its registry with the credentials from ResolveAuth
+func ResolveDigest(ctx context.Context, imageRef string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %q: %w", imageRef, err)
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return "", fmt.Errorf("image reference %q is already pinned to a digest", imageRef)
+	}
+	tagged, ok := reference.TagNameOnly(named).(reference.Tagged)
+	if !ok {
+		return "", fmt.Errorf("image reference %q has no tag", imageRef)
+	}
+
+	host := reference.Domain(named)
+	scheme := "https"
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+	} else if strings.HasPrefix(host, "localhost") || strings.HasPrefix(host, "127.0.0.1") {
+		scheme = "http"
+	}
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, host, reference.Path(named), tagged.Tag())
+
+	resp, err := manifestRequest(ctx, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err := registryAuthorization(ctx, imageRef, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", err
+		}
+		if resp, err = manifestRequest(ctx, manifestURL, authorization); err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("image %s not found in its registry", imageRef)
+	default:
+		return "", fmt.Errorf("registry returned %s for %s", resp.Status, imageRef)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry did not report a digest for %s", imageRef)
+	}
+	return digest, nil
+}
+
+// manifestRequest sends a HEAD request for a manifest
+func manifestRequest(ctx context.Context, manifestURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry request: %w", err)
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := registryHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query registry: %w", err)
+	}
+	return resp, nil
+}
+
+// registryAuthorization answers a WWW-Authenticate challenge, fetching a
+// bearer token when the registry uses token authentication
+func registryAuthorization(ctx context.Context, imageRef, challenge string) (string, error) {
+	auth, err := ResolveAuth(imageRef)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve registry credentials: %w", err)
+	}
+
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if auth.Username == "" {
+			return "", fmt.Errorf("registry of %s requires credentials", imageRef)
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password)), nil
+
+	case "bearer":
+		tokenURL, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return "", fmt.Errorf("invalid registry token realm %q", params["realm"])
+		}
+		query := tokenURL.Query()
+		for _, key := range []string{"service", "scope"} {
+			if params[key] != "" {
+				query.Set(key, params[key])
+			}
+		}
+		tokenURL.RawQuery = query.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to create token request: %w", err)
+		}
+		if auth.Username != "" {
+			req.SetBasicAuth(auth.Username, auth.Password)
+		}
+		resp, err := registryHTTPClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch registry token: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("registry token request returned %s", resp.Status)
+		}
+
+		var token struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+			return "", fmt.Errorf("failed to parse registry token: %w", err)
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		return "Bearer " + token.Token, nil
+
+	default:
+		return "", fmt.Errorf("unsupported registry authentication %q", scheme)
+	}
+}
+
+// parseChallenge splits a WWW-Authenticate header into its scheme and parameters
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, ", "), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key = strings.TrimSpace(key); key != "" {
+			params[strings.ToLower(key)] = value
+		}
+	}
+	return scheme, params
+}
+
+// ImageDigest returns the digest imageRef's tag points to according to the
+// engine. Without registry access, the digest of the pulled image is used.
+func (c *Client) ImageDigest(ctx context.Context, imageRef string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %q: %w", imageRef, err)
+	}
+
+	auth, err := EncodeAuth(imageRef)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve registry credentials: %w", err)
+	}
+	info, err := c.cli.DistributionInspect(ctx, imageRef, auth)
+	if err == nil {
+		return string(info.Descriptor.Digest), nil
+	}
+
+	local, inspectErr := c.cli.ImageInspect(ctx, imageRef)
+	if inspectErr != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", imageRef, err)
+	}
+	for _, repoDigest := range local.RepoDigests {
+		repo, digest, ok := strings.Cut(repoDigest, "@")
+		if ok && repo == named.Name() {
+			return digest, nil
+		}
+	}
+	return "", fmt.Errorf("failed to resolve %s: %w", imageRef, err)
+}
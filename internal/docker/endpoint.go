@@ -0,0 +1,221 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint describes how to reach a container engine
+type Endpoint struct {
+	Host   string
+	Source string // flag, env, context:<name>, podman or default
+	TLSDir string // directory holding ca.pem, cert.pem and key.pem, if any
+}
+
+// contextMeta is the subset of ~/.docker/contexts/meta/<id>/meta.json used by vess
+type contextMeta struct {
+	Name      string `json:"Name"`
+	Endpoints map[string]struct {
+		Host string `json:"Host"`
+	} `json:"Endpoints"`
+}
+
+// defaultDockerSocket is the engine socket used when nothing else is configured
+const defaultDockerSocket = "/var/run/docker.sock"
+
+// ResolveEndpoint determines the engine endpoint. The order is: the host
+// override, DOCKER_HOST, DOCKER_CONTEXT or the current context from the Docker
+// config, the default Docker socket, and finally a rootless or rootful
+// Podman socket.
+func ResolveEndpoint(hostOverride string) (*Endpoint, error) {
+	if hostOverride != "" {
+		return &Endpoint{Host: hostOverride, Source: "flag"}, nil
+	}
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return &Endpoint{Host: host, Source: "env"}, nil
+	}
+
+	contextName := os.Getenv("DOCKER_CONTEXT")
+	if contextName == "" {
+		cfg, err := loadDockerConfigContext()
+		if err != nil {
+			return nil, err
+		}
+		contextName = cfg
+	}
+	if contextName != "" && contextName != "default" {
+		return loadContextEndpoint(contextName)
+	}
+
+	if _, err := os.Stat(defaultDockerSocket); err == nil {
+		return &Endpoint{Host: "unix://" + defaultDockerSocket, Source: "default"}, nil
+	}
+	for _, socket := range podmanSockets() {
+		if _, err := os.Stat(socket); err == nil {
+			return &Endpoint{Host: "unix://" + socket, Source: "podman"}, nil
+		}
+	}
+
+	return &Endpoint{Host: "unix://" + defaultDockerSocket, Source: "default"}, nil
+}
+
+// loadDockerConfigContext returns currentContext from the Docker config
+func loadDockerConfigContext() (string, error) {
+	data, err := os.ReadFile(filepath.Join(DockerConfigDir(), "config.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read Docker config: %w", err)
+	}
+
+	var cfg struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return "", fmt.Errorf("failed to parse Docker config: %w", err)
+	}
+	return cfg.CurrentContext, nil
+}
+
+// loadContextEndpoint reads the docker endpoint of a named Docker context
+func loadContextEndpoint(name string) (*Endpoint, error) {
+	sum := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(sum[:])
+	contextsDir := filepath.Join(DockerConfigDir(), "contexts")
+
+	data, err := os.ReadFile(filepath.Join(contextsDir, "meta", id, "meta.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Docker context %q: %w", name, err)
+	}
+
+	var meta contextMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse Docker context %q: %w", name, err)
+	}
+
+	ep, ok := meta.Endpoints["docker"]
+	if !ok || ep.Host == "" {
+		return nil, fmt.Errorf("docker context %q has no docker endpoint", name)
+	}
+
+	endpoint := &Endpoint{
+		Host:   ep.Host,
+		Source: "context:" + name,
+	}
+	tlsDir := filepath.Join(contextsDir, "tls", id, "docker")
+	if _, err := os.Stat(filepath.Join(tlsDir, "ca.pem")); err == nil {
+		endpoint.TLSDir = tlsDir
+	}
+	return endpoint, nil
+}
+
+// podmanSockets returns the well-known Podman API socket paths
+func podmanSockets() []string {
+	var sockets []string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		sockets = append(sockets, filepath.Join(runtimeDir, "podman", "podman.sock"))
+	}
+	sockets = append(sockets, fmt.Sprintf("/run/user/%d/podman/podman.sock", os.Getuid()))
+	return append(sockets, "/run/podman/podman.sock")
+}
+
+// sshDialer returns a dialer that tunnels the engine API over
+// `ssh <host> docker system dial-stdio`, like the Docker CLI does.
+func sshDialer(host string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh host %q: %w", host, err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid ssh host %q: no hostname", host)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return nil, fmt.Errorf("invalid ssh host %q: paths are not supported", host)
+	}
+
+	args := []string{"-o", "ConnectTimeout=30", "-T"}
+	if u.User != nil {
+		args = append(args, "-l", u.User.Username())
+	}
+	if port := u.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	args = append(args, "--", u.Hostname(), "docker", "system", "dial-stdio")
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return newCommandConn(ctx, "ssh", args...)
+	}, nil
+}
+
+// commandConn is a net.Conn backed by the stdin/stdout of a command
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	once   sync.Once
+}
+
+func newCommandConn(ctx context.Context, name string, args ...string) (net.Conn, error) {
+	// The connection outlives the dial context, so do not tie the process to it
+	cmd := exec.CommandContext(context.WithoutCancel(ctx), name, args...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", name, err)
+	}
+
+	return &commandConn{cmd: cmd, stdin: stdin, stdout: stdout}, nil
+}
+
+func (c *commandConn) Read(p []byte) (int, error)  { return c.stdout.Read(p) }
+func (c *commandConn) Write(p []byte) (int, error) { return c.stdin.Write(p) }
+
+func (c *commandConn) Close() error {
+	c.once.Do(func() {
+		c.stdin.Close()
+		c.stdout.Close()
+		if c.cmd.Process != nil {
+			_ = c.cmd.Process.Kill()
+		}
+		_ = c.cmd.Wait()
+	})
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr                { return dummyAddr{} }
+func (c *commandConn) RemoteAddr() net.Addr               { return dummyAddr{} }
+func (c *commandConn) SetDeadline(t time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// dummyAddr is the address of a commandConn
+type dummyAddr struct{}
+
+func (dummyAddr) Network() string { return "command" }
+func (dummyAddr) String() string  { return "command" }
+
+// isSSHHost reports whether host uses the ssh:// scheme
+func isSSHHost(host string) bool {
+	return strings.HasPrefix(host, "ssh://")
+}
@@ -0,0 +1,153 @@
+package docker
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/image"
+)
+
+// ImageEntry is a single repository:tag of an image (or an untagged image)
+type ImageEntry struct {
+	ID         string            `json:"id"`
+	Repository string            `json:"repository"`
+	Tag        string            `json:"tag"`
+	Created    time.Time         `json:"created"`
+	Size       int64             `json:"size"`
+	Labels     map[string]string `json:"labels"`
+}
+
+// Reference returns the reference used to remove this entry
+func (e *ImageEntry) Reference() string {
+	if e.Dangling() {
+		return e.ID
+	}
+	return e.Repository + ":" + e.Tag
+}
+
+// Dangling reports whether the entry has no tag
+func (e *ImageEntry) Dangling() bool {
+	return e.Repository == "" || e.Repository == "<none>"
+}
+
+// ExpandImages turns image summaries into one entry per tag, newest first
+func ExpandImages(images []image.Summary) []*ImageEntry {
+	entries := make([]*ImageEntry, 0, len(images))
+
+	for _, img := range images {
+		base := ImageEntry{
+			ID:      img.ID,
+			Created: time.Unix(img.Created, 0),
+			Size:    img.Size,
+			Labels:  img.Labels,
+		}
+
+		tags := img.RepoTags
+		if len(tags) == 0 {
+			tags = []string{"<none>:<none>"}
+		}
+		for _, repoTag := range tags {
+			entry := base
+			if i := strings.LastIndex(repoTag, ":"); i > 0 && !strings.Contains(repoTag[i:], "/") {
+				entry.Repository, entry.Tag = repoTag[:i], repoTag[i+1:]
+			} else {
+				entry.Repository, entry.Tag = repoTag, "latest"
+			}
+			entries = append(entries, &entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Created.After(entries[j].Created)
+	})
+	return entries
+}
+
+// PruneOptions selects images to remove
+type PruneOptions struct {
+	// OlderThan selects images created more than this long ago
+	OlderThan time.Duration
+	// KeepPerRepo selects all but the newest N tags of each repository
+	KeepPerRepo int
+	// TagPattern restricts candidates to repository:tag values matching this glob
+	TagPattern string
+	// Dangling selects untagged images
+	Dangling bool
+	// Now is the reference time for OlderThan; defaults to time.Now()
+	Now time.Time
+}
+
+// SelectForPrune returns the entries matching opts. Entries must be sorted newest
+// first, as returned by ExpandImages. When no selection criterion is set, every
+// entry matching TagPattern is selected.
+func SelectForPrune(entries []*ImageEntry, opts PruneOptions) ([]*ImageEntry, error) {
+	if opts.TagPattern != "" {
+		if _, err := path.Match(opts.TagPattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid tag pattern %q: %w", opts.TagPattern, err)
+		}
+	}
+	if opts.KeepPerRepo < 0 {
+		return nil, fmt.Errorf("keep count must not be negative")
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	hasCriteria := opts.OlderThan > 0 || opts.KeepPerRepo > 0 || opts.Dangling
+
+	seen := make(map[string]int)
+	var selected []*ImageEntry
+
+	for _, entry := range entries {
+		if opts.TagPattern != "" {
+			if entry.Dangling() {
+				continue
+			}
+			if ok, _ := path.Match(opts.TagPattern, entry.Repository+":"+entry.Tag); !ok {
+				continue
+			}
+		}
+
+		match := !hasCriteria
+		if opts.Dangling && entry.Dangling() {
+			match = true
+		}
+		if opts.OlderThan > 0 && now.Sub(entry.Created) > opts.OlderThan {
+			match = true
+		}
+		if opts.KeepPerRepo > 0 && !entry.Dangling() {
+			seen[entry.Repository]++
+			if seen[entry.Repository] > opts.KeepPerRepo {
+				match = true
+			}
+		}
+
+		if match {
+			selected = append(selected, entry)
+		}
+	}
+
+	return selected, nil
+}
+
+// ParseAge parses a duration that may also use a "d" (days) suffix, e.g. "14d"
+func ParseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age: %s", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age: %s", value)
+	}
+	return d, nil
+}
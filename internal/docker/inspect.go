@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ImageMetadata describes a built image
+type ImageMetadata struct {
+	Reference    string            `json:"reference"`
+	ID           string            `json:"id"`
+	RepoTags     []string          `json:"repo_tags"`
+	RepoDigests  []string          `json:"repo_digests"`
+	Size         int64             `json:"size"`
+	Created      time.Time         `json:"created"`
+	Architecture string            `json:"architecture"`
+	OS           string            `json:"os"`
+	LayerCount   int               `json:"layer_count"`
+	Layers       []LayerMetadata   `json:"layers"`
+	Labels       map[string]string `json:"labels"`
+}
+
+// LayerMetadata describes a single image history entry that added data
+type LayerMetadata struct {
+	ID        string `json:"id,omitempty"`
+	CreatedBy string `json:"created_by"`
+	Size      int64  `json:"size"`
+}
+
+// InspectImage collects metadata for an image by tag or ID
+func (b *Builder) InspectImage(ctx context.Context, ref string) (*ImageMetadata, error) {
+	cli := b.client.GetClient()
+
+	info, err := cli.ImageInspect(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image: %w", err)
+	}
+
+	history, err := cli.ImageHistory(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image history: %w", err)
+	}
+
+	meta := &ImageMetadata{
+		Reference:    ref,
+		ID:           info.ID,
+		RepoTags:     info.RepoTags,
+		RepoDigests:  info.RepoDigests,
+		Size:         info.Size,
+		Architecture: info.Architecture,
+		OS:           info.Os,
+		LayerCount:   len(info.RootFS.Layers),
+		Layers:       make([]LayerMetadata, 0, len(history)),
+		Labels:       map[string]string{},
+	}
+
+	if created, err := time.Parse(time.RFC3339Nano, info.Created); err == nil {
+		meta.Created = created
+	}
+	if info.Config != nil && info.Config.Labels != nil {
+		meta.Labels = info.Config.Labels
+	}
+
+	// History is newest first; report layers in build order
+	for i := len(history) - 1; i >= 0; i-- {
+		item := history[i]
+		if item.Size == 0 {
+			continue
+		}
+		id := item.ID
+		if id == "<missing>" {
+			id = ""
+		}
+		meta.Layers = append(meta.Layers, LayerMetadata{
+			ID:        id,
+			CreatedBy: item.CreatedBy,
+			Size:      item.Size,
+		})
+	}
+
+	return meta, nil
+}
+
+// FormatSize formats a byte count for display
+func FormatSize(n int64) string {
+	return formatBytes(n)
+}
@@ -0,0 +1,92 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// apkInstallingPattern matches the "(1/3) Installing name (version)" lines of apk add --simulate
+var apkInstallingPattern = regexp.MustCompile(`^\(\d+/\d+\) Installing (\S+) \((\S+)\)`)
+
+// PackageVersions returns the version of each package the package manager
+// (apk or apt-get) of imageRef installs. The container needs network access
+// to fetch the package index. Packages that are already installed in the
+// image resolve to the installed version.
+func (c *Client) PackageVersions(ctx context.Context, imageRef, manager string, packages []string) (map[string]string, error) {
+	var script string
+	switch manager {
+	case "apk":
+		script = "apk list --installed 2>/dev/null | sed 's/^/installed /'; apk add --simulate --no-cache " + strings.Join(packages, " ")
+	case "apt-get":
+		script = "apt-get update -qq >/dev/null && apt-cache policy " + strings.Join(packages, " ")
+	default:
+		return nil, fmt.Errorf("unsupported package manager: %s", manager)
+	}
+
+	result, err := c.runContainer(ctx, imageRef, []string{"sh", "-c", script}, "")
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("failed to resolve packages on %s: %s", imageRef, strings.TrimSpace(result.Stderr))
+	}
+
+	var available map[string]string
+	if manager == "apk" {
+		available = parseAPKVersions(result.Stdout, packages)
+	} else {
+		available = parseAptPolicy(result.Stdout)
+	}
+
+	versions := make(map[string]string, len(packages))
+	for _, pkg := range packages {
+		version := available[pkg]
+		if version == "" {
+			return nil, fmt.Errorf("package %s is not available on %s", pkg, imageRef)
+		}
+		versions[pkg] = version
+	}
+	return versions, nil
+}
+
+// parseAPKVersions reads the versions of packages from apk list --installed
+// lines (prefixed with "installed ") and apk add --simulate output
+func parseAPKVersions(output string, packages []string) map[string]string {
+	versions := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		if m := apkInstallingPattern.FindStringSubmatch(line); m != nil {
+			versions[m[1]] = m[2]
+			continue
+		}
+		// installed name-version arch {origin} (license) [installed]
+		fields := strings.Fields(strings.TrimPrefix(line, "installed "))
+		if !strings.HasPrefix(line, "installed ") || len(fields) == 0 {
+			continue
+		}
+		for _, pkg := range packages {
+			version, ok := strings.CutPrefix(fields[0], pkg+"-")
+			if ok && version != "" && version[0] >= '0' && version[0] <= '9' && strings.Contains(version, "-r") {
+				versions[pkg] = version
+			}
+		}
+	}
+	return versions
+}
+
+// parseAptPolicy reads the candidate versions from apt-cache policy output
+func parseAptPolicy(output string) map[string]string {
+	versions := make(map[string]string)
+	var pkg string
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" && line[0] != ' ' && strings.HasSuffix(line, ":") {
+			pkg = strings.TrimSuffix(line, ":")
+			continue
+		}
+		if candidate, ok := strings.CutPrefix(strings.TrimSpace(line), "Candidate: "); ok && pkg != "" && candidate != "(none)" {
+			versions[pkg] = candidate
+		}
+	}
+	return versions
+}
@@ -0,0 +1,379 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Progress modes accepted by NewProgressRenderer
+const (
+	ProgressAuto  = "auto"
+	ProgressPlain = "plain"
+	ProgressTTY   = "tty"
+	ProgressJSON  = "json"
+)
+
+// Event types emitted while processing a Docker message stream
+const (
+	EventStep     = "step"
+	EventStepDone = "step_done"
+	EventLog      = "log"
+	EventStatus   = "status"
+	EventProgress = "progress"
+	EventImage    = "image"
+	EventDigest   = "digest"
+	EventError    = "error"
+)
+
+// JSONMessage is a single message from the Docker build, pull or push stream
+type JSONMessage struct {
+	Stream         string `json:"stream,omitempty"`
+	Status         string `json:"status,omitempty"`
+	Progress       string `json:"progress,omitempty"`
+	ProgressDetail struct {
+		Current int64 `json:"current,omitempty"`
+		Total   int64 `json:"total,omitempty"`
+	} `json:"progressDetail"`
+	ID          string          `json:"id,omitempty"`
+	Aux         json.RawMessage `json:"aux,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	ErrorDetail struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+}
+
+// auxMessage covers the aux payloads sent by the build (ID) and push (Tag, Digest, Size) endpoints
+type auxMessage struct {
+	ID     string `json:"ID"`
+	Tag    string `json:"Tag"`
+	Digest string `json:"Digest"`
+	Size   int64  `json:"Size"`
+}
+
+// ProgressEvent is a normalized event derived from the Docker message stream
+type ProgressEvent struct {
+	Time        time.Time `json:"time"`
+	Type        string    `json:"type"`
+	Step        int       `json:"step,omitempty"`
+	TotalSteps  int       `json:"total_steps,omitempty"`
+	Instruction string    `json:"instruction,omitempty"`
+	ID          string    `json:"id,omitempty"`
+	Message     string    `json:"message,omitempty"`
+	Current     int64     `json:"current,omitempty"`
+	Total       int64     `json:"total,omitempty"`
+	ElapsedMS   int64     `json:"elapsed_ms,omitempty"`
+	ImageID     string    `json:"image_id,omitempty"`
+	Digest      string    `json:"digest,omitempty"`
+}
+
+// ProgressRenderer renders progress events
+type ProgressRenderer interface {
+	Render(ev *ProgressEvent)
+	Close()
+}
+
+// NewProgressRenderer creates a renderer for the given mode writing to w
+func NewProgressRenderer(mode string, w io.Writer) (ProgressRenderer, error) {
+	switch mode {
+	case "", ProgressAuto:
+		if isTerminal(w) {
+			return newTTYRenderer(w), nil
+		}
+		return &plainRenderer{out: w}, nil
+	case ProgressPlain:
+		return &plainRenderer{out: w}, nil
+	case ProgressTTY:
+		return newTTYRenderer(w), nil
+	case ProgressJSON:
+		return &jsonRenderer{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported progress mode: %s (must be one of: auto, plain, tty, json)", mode)
+	}
+}
+
+// isTerminal reports whether w is a character device
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// StepResult records the duration of a single build step
+type StepResult struct {
+	Number      int           `json:"number"`
+	Instruction string        `json:"instruction"`
+	Duration    time.Duration `json:"duration"`
+}
+
+var stepPattern = regexp.MustCompile(`^Step (\d+)/(\d+) : (.*)$`)
+
+// streamTracker turns Docker JSON messages into progress events
+type streamTracker struct {
+	renderer ProgressRenderer
+
+	step        int
+	totalSteps  int
+	instruction string
+	stepStarted time.Time
+
+	steps      []StepResult
+	imageID    string
+	digest     string
+	digestSize int64
+}
+
+func newStreamTracker(renderer ProgressRenderer) *streamTracker {
+	return &streamTracker{renderer: renderer}
+}
+
+// consume decodes the stream until EOF, returning the first error reported by the daemon
+func (t *streamTracker) consume(reader io.Reader) error {
+	decoder := json.NewDecoder(reader)
+
+	for {
+		var message JSONMessage
+		if err := decoder.Decode(&message); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+
+		if err := t.handle(&message); err != nil {
+			return err
+		}
+	}
+
+	t.finishStep()
+	return nil
+}
+
+// handle processes a single message
+func (t *streamTracker) handle(message *JSONMessage) error {
+	now := time.Now()
+
+	if message.Error != "" {
+		t.emit(&ProgressEvent{Time: now, Type: EventError, Step: t.step, Message: message.Error})
+		return fmt.Errorf("%s", message.Error)
+	}
+
+	if len(message.Aux) > 0 {
+		var aux auxMessage
+		if err := json.Unmarshal(message.Aux, &aux); err == nil {
+			if aux.ID != "" {
+				t.imageID = aux.ID
+				t.emit(&ProgressEvent{Time: now, Type: EventImage, ImageID: aux.ID})
+			}
+			if aux.Digest != "" {
+				t.digest = aux.Digest
+				t.digestSize = aux.Size
+				t.emit(&ProgressEvent{Time: now, Type: EventDigest, ID: aux.Tag, Digest: aux.Digest, Total: aux.Size})
+			}
+		}
+	}
+
+	if message.Stream != "" {
+		line := strings.TrimRight(message.Stream, "\r\n")
+		if m := stepPattern.FindStringSubmatch(line); m != nil {
+			t.finishStep()
+			t.step, _ = strconv.Atoi(m[1])
+			t.totalSteps, _ = strconv.Atoi(m[2])
+			t.instruction = m[3]
+			t.stepStarted = now
+			t.emit(&ProgressEvent{Time: now, Type: EventStep, Step: t.step, TotalSteps: t.totalSteps, Instruction: t.instruction})
+		} else if strings.TrimSpace(line) != "" {
+			if id, ok := strings.CutPrefix(line, "Successfully built "); ok && t.imageID == "" {
+				t.imageID = strings.TrimSpace(id)
+			}
+			t.emit(&ProgressEvent{Time: now, Type: EventLog, Step: t.step, Message: line})
+		}
+	}
+
+	if message.Status != "" {
+		ev := &ProgressEvent{Time: now, Type: EventStatus, Step: t.step, ID: message.ID, Message: message.Status}
+		if message.ProgressDetail.Total > 0 || message.Progress != "" {
+			ev.Type = EventProgress
+			ev.Current = message.ProgressDetail.Current
+			ev.Total = message.ProgressDetail.Total
+		}
+		t.emit(ev)
+	}
+
+	return nil
+}
+
+// finishStep closes the current step, if any, and records its elapsed time
+func (t *streamTracker) finishStep() {
+	if t.step == 0 {
+		return
+	}
+
+	elapsed := time.Since(t.stepStarted)
+	t.steps = append(t.steps, StepResult{Number: t.step, Instruction: t.instruction, Duration: elapsed})
+	t.emit(&ProgressEvent{
+		Time:        time.Now(),
+		Type:        EventStepDone,
+		Step:        t.step,
+		TotalSteps:  t.totalSteps,
+		Instruction: t.instruction,
+		ElapsedMS:   elapsed.Milliseconds(),
+	})
+	t.step = 0
+}
+
+// currentStep describes the step in progress for error reporting
+func (t *streamTracker) currentStep() string {
+	if t.step == 0 {
+		return ""
+	}
+	return fmt.Sprintf("step %d/%d (%s)", t.step, t.totalSteps, t.instruction)
+}
+
+func (t *streamTracker) emit(ev *ProgressEvent) {
+	if t.renderer != nil {
+		t.renderer.Render(ev)
+	}
+}
+
+// plainRenderer writes one line per event, suitable for log files and CI
+type plainRenderer struct {
+	out      io.Writer
+	statuses map[string]string
+}
+
+func (r *plainRenderer) Render(ev *ProgressEvent) {
+	switch ev.Type {
+	case EventStep:
+		fmt.Fprintf(r.out, "#%d/%d %s\n", ev.Step, ev.TotalSteps, ev.Instruction)
+	case EventStepDone:
+		fmt.Fprintf(r.out, "#%d/%d done in %s\n", ev.Step, ev.TotalSteps, formatElapsed(ev.ElapsedMS))
+	case EventLog:
+		fmt.Fprintf(r.out, "  %s\n", ev.Message)
+	case EventStatus, EventProgress:
+		// Only print status transitions, not every progress tick
+		if r.statuses == nil {
+			r.statuses = make(map[string]string)
+		}
+		if r.statuses[ev.ID] == ev.Message {
+			return
+		}
+		r.statuses[ev.ID] = ev.Message
+		if ev.ID != "" {
+			fmt.Fprintf(r.out, "  %s: %s\n", ev.ID, ev.Message)
+		} else {
+			fmt.Fprintf(r.out, "  %s\n", ev.Message)
+		}
+	case EventImage:
+		fmt.Fprintf(r.out, "image: %s\n", ev.ImageID)
+	case EventDigest:
+		fmt.Fprintf(r.out, "%s: digest: %s size: %d\n", ev.ID, ev.Digest, ev.Total)
+	case EventError:
+		fmt.Fprintf(r.out, "error: %s\n", ev.Message)
+	}
+}
+
+func (r *plainRenderer) Close() {}
+
+// ttyRenderer redraws pull/push progress in place on an interactive terminal
+type ttyRenderer struct {
+	plain      *plainRenderer
+	out        io.Writer
+	progressID string
+}
+
+func newTTYRenderer(w io.Writer) *ttyRenderer {
+	return &ttyRenderer{plain: &plainRenderer{out: w}, out: w}
+}
+
+func (r *ttyRenderer) Render(ev *ProgressEvent) {
+	if ev.Type == EventProgress {
+		if r.progressID != "" && r.progressID != ev.ID {
+			fmt.Fprintln(r.out)
+		}
+		r.progressID = ev.ID
+		fmt.Fprintf(r.out, "\r\033[K  %s: %s %s", ev.ID, ev.Message, progressBar(ev.Current, ev.Total))
+		return
+	}
+
+	r.endProgressLine()
+	switch ev.Type {
+	case EventStep:
+		fmt.Fprintf(r.out, "\033[1m[%d/%d]\033[0m %s\n", ev.Step, ev.TotalSteps, ev.Instruction)
+	case EventStepDone:
+		fmt.Fprintf(r.out, "\033[2m[%d/%d] done in %s\033[0m\n", ev.Step, ev.TotalSteps, formatElapsed(ev.ElapsedMS))
+	default:
+		r.plain.Render(ev)
+	}
+}
+
+func (r *ttyRenderer) Close() {
+	r.endProgressLine()
+}
+
+func (r *ttyRenderer) endProgressLine() {
+	if r.progressID != "" {
+		fmt.Fprintln(r.out)
+		r.progressID = ""
+	}
+}
+
+// jsonRenderer writes one JSON object per event
+type jsonRenderer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (r *jsonRenderer) Render(ev *ProgressEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.enc.Encode(ev)
+}
+
+func (r *jsonRenderer) Close() {}
+
+// progressBar renders a fixed-width progress bar
+func progressBar(current, total int64) string {
+	const width = 30
+	if total <= 0 {
+		return formatBytes(current)
+	}
+
+	filled := int(float64(width) * float64(current) / float64(total))
+	if filled > width {
+		filled = width
+	}
+	return fmt.Sprintf("[%s%s] %s/%s", strings.Repeat("=", filled), strings.Repeat(" ", width-filled),
+		formatBytes(current), formatBytes(total))
+}
+
+// formatBytes formats a byte count using binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatElapsed formats milliseconds as a short duration
+func formatElapsed(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+}
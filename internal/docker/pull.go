@@ -0,0 +1,53 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/docker/docker/api/types/image"
+)
+
+// Pull pulls an image from its registry using credentials from ResolveAuth
+func (b *Builder) Pull(ctx context.Context, imageRef string, progress ProgressRenderer) error {
+	if err := b.client.Ping(ctx); err != nil {
+		return err
+	}
+
+	auth, err := EncodeAuth(imageRef)
+	if err != nil {
+		return fmt.Errorf("failed to resolve registry credentials: %w", err)
+	}
+
+	if progress == nil {
+		progress = &plainRenderer{out: os.Stdout}
+	}
+	defer progress.Close()
+	tracker := newStreamTracker(progress)
+
+	b.logger.Debug("Pulling %s...", imageRef)
+	resp, err := b.client.GetClient().ImagePull(ctx, imageRef, image.PullOptions{RegistryAuth: auth})
+	if err != nil {
+		if ctx.Err() != nil {
+			return interrupted(ctx, "pull", "")
+		}
+		return fmt.Errorf("failed to pull image: %w", err)
+	}
+	defer resp.Close()
+
+	if err := tracker.consume(resp); err != nil {
+		if ctx.Err() != nil {
+			return interrupted(ctx, "pull", "")
+		}
+		return fmt.Errorf("pull failed: %w", err)
+	}
+	return nil
+}
+
+// TagImage adds the target reference to the source image
+func (b *Builder) TagImage(ctx context.Context, source, target string) error {
+	if err := b.client.GetClient().ImageTag(ctx, source, target); err != nil {
+		return fmt.Errorf("failed to tag %s as %s: %w", source, target, err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/docker/docker/api/types/image"
+)
+
+// PushResult describes a pushed image
+type PushResult struct {
+	Reference string `json:"reference"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// Push pushes an image to its registry using credentials from ResolveAuth
+func (b *Builder) Push(ctx context.Context, imageRef string, progress ProgressRenderer) (*PushResult, error) {
+	if err := b.client.Ping(ctx); err != nil {
+		return nil, err
+	}
+
+	auth, err := EncodeAuth(imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve registry credentials: %w", err)
+	}
+
+	if progress == nil {
+		progress = &plainRenderer{out: os.Stdout}
+	}
+	defer progress.Close()
+	tracker := newStreamTracker(progress)
+
+	b.logger.Debug("Pushing %s...", imageRef)
+	resp, err := b.client.GetClient().ImagePush(ctx, imageRef, image.PushOptions{RegistryAuth: auth})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, interrupted(ctx, "push", "")
+		}
+		return nil, fmt.Errorf("failed to push image: %w", err)
+	}
+	defer resp.Close()
+
+	if err := tracker.consume(resp); err != nil {
+		if ctx.Err() != nil {
+			return nil, interrupted(ctx, "push", "")
+		}
+		return nil, fmt.Errorf("push failed: %w", err)
+	}
+
+	if tracker.digest == "" {
+		return nil, fmt.Errorf("push of %s finished without reporting a digest", imageRef)
+	}
+
+	return &PushResult{
+		Reference: imageRef,
+		Digest:    tracker.digest,
+		Size:      tracker.digestSize,
+	}, nil
+}
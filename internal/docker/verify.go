@@ -0,0 +1,206 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"vess/internal/extensions"
+)
+
+// Verifier checks that a built image loads the expected PHP extensions
+type Verifier struct {
+	client *Client
+	logger Logger
+}
+
+// VerifyResult holds the outcome of an image verification
+type VerifyResult struct {
+	Image           string   `json:"image"`
+	ExpectedVersion string   `json:"expected_php_version"`
+	PHPVersion      string   `json:"php_version"`
+	Loaded          []string `json:"loaded"`
+	Missing         []string `json:"missing"`
+	Unexpected      []string `json:"unexpected"`
+	// NotEnabled lists missing extensions whose shared object exists in extension_dir
+	NotEnabled []string `json:"not_enabled"`
+}
+
+// OK reports whether the image matched the expectations
+func (r *VerifyResult) OK() bool {
+	return len(r.Missing) == 0 && r.VersionMatches()
+}
+
+// VersionMatches reports whether the running PHP version matches the expected minor version
+func (r *VerifyResult) VersionMatches() bool {
+	return r.ExpectedVersion == "" ||
+		r.PHPVersion == r.ExpectedVersion ||
+		strings.HasPrefix(r.PHPVersion, r.ExpectedVersion+".")
+}
+
+// Diff returns a human-readable diff of expected versus loaded modules
+func (r *VerifyResult) Diff() []string {
+	lines := make([]string, 0, len(r.Missing)+len(r.Unexpected)+1)
+
+	if !r.VersionMatches() {
+		lines = append(lines, fmt.Sprintf("- PHP %s (expected)", r.ExpectedVersion))
+		lines = append(lines, fmt.Sprintf("+ PHP %s (running)", r.PHPVersion))
+	}
+	for _, name := range r.Missing {
+		if contains(r.NotEnabled, name) {
+			lines = append(lines, fmt.Sprintf("- %s (compiled but not enabled: no ini loads %s.so)", name, name))
+		} else {
+			lines = append(lines, fmt.Sprintf("- %s (expected, not loaded)", name))
+		}
+	}
+	for _, name := range r.Unexpected {
+		lines = append(lines, fmt.Sprintf("+ %s (loaded, not configured)", name))
+	}
+
+	return lines
+}
+
+var (
+	phpVersionPattern   = regexp.MustCompile(`(?m)^PHP Version => (\S+)`)
+	extensionDirPattern = regexp.MustCompile(`(?m)^extension_dir => (\S+)`)
+)
+
+// NewVerifier creates a new image verifier
+func NewVerifier(client *Client, logger Logger) *Verifier {
+	return &Verifier{
+		client: client,
+		logger: logger,
+	}
+}
+
+// Verify runs `php -m` and `php -i` in a throwaway container and compares the
+// loaded modules with the configured extensions plus the image builtins.
+func (v *Verifier) Verify(ctx context.Context, imageRef string, extNames []string, phpVersion string) (*VerifyResult, error) {
+	if err := v.client.Ping(ctx); err != nil {
+		return nil, err
+	}
+
+	v.logger.Debug("Running php -m in %s", imageRef)
+	modules, err := v.run(ctx, imageRef, "php", "-m")
+	if err != nil {
+		return nil, err
+	}
+
+	v.logger.Debug("Running php -i in %s", imageRef)
+	info, err := v.run(ctx, imageRef, "php", "-i")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VerifyResult{
+		Image:           imageRef,
+		ExpectedVersion: phpVersion,
+		Loaded:          parseModules(modules),
+	}
+	if m := phpVersionPattern.FindStringSubmatch(info); m != nil {
+		result.PHPVersion = m[1]
+	}
+
+	loaded := make(map[string]string, len(result.Loaded))
+	for _, name := range result.Loaded {
+		loaded[strings.ToLower(name)] = name
+	}
+
+	expected := make(map[string]bool)
+	for _, name := range extensions.GetBuiltinModules() {
+		expected[strings.ToLower(name)] = true
+	}
+	for _, extName := range extNames {
+		module := extensions.GetModuleName(extName)
+		expected[strings.ToLower(module)] = true
+		if _, ok := loaded[strings.ToLower(module)]; !ok {
+			result.Missing = append(result.Missing, extName)
+		}
+	}
+	for _, name := range extensions.GetBuiltinModules() {
+		if _, ok := loaded[strings.ToLower(name)]; !ok {
+			result.Missing = append(result.Missing, name)
+		}
+	}
+	for lower, name := range loaded {
+		if !expected[lower] {
+			result.Unexpected = append(result.Unexpected, name)
+		}
+	}
+	sort.Strings(result.Unexpected)
+
+	if len(result.Missing) > 0 {
+		result.NotEnabled = v.findNotEnabled(ctx, imageRef, info, result.Missing)
+	}
+
+	return result, nil
+}
+
+// run runs a command and returns its stdout, failing on a non-zero exit code
+func (v *Verifier) run(ctx context.Context, imageRef string, cmd ...string) (string, error) {
+	res, err := v.client.RunCommand(ctx, imageRef, cmd)
+	if err != nil {
+		return "", err
+	}
+	if res.ExitCode != 0 {
+		return "", fmt.Errorf("%s exited with code %d: %s", strings.Join(cmd, " "), res.ExitCode, strings.TrimSpace(res.Stderr))
+	}
+	return res.Stdout, nil
+}
+
+// findNotEnabled returns the missing extensions whose .so file is present in extension_dir
+func (v *Verifier) findNotEnabled(ctx context.Context, imageRef, info string, missing []string) []string {
+	m := extensionDirPattern.FindStringSubmatch(info)
+	if m == nil {
+		return nil
+	}
+
+	listing, err := v.run(ctx, imageRef, "ls", "-1", m[1])
+	if err != nil {
+		v.logger.Debug("Could not list extension_dir: %v", err)
+		return nil
+	}
+
+	files := strings.Fields(listing)
+	var notEnabled []string
+	for _, name := range missing {
+		if contains(files, name+".so") {
+			notEnabled = append(notEnabled, name)
+		}
+	}
+	return notEnabled
+}
+
+// parseModules parses `php -m` output into module names
+func parseModules(output string) []string {
+	seen := make(map[string]bool)
+	var modules []string
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "[") {
+			continue
+		}
+		if !seen[line] {
+			seen[line] = true
+			modules = append(modules, line)
+		}
+	}
+
+	sort.Strings(modules)
+	return modules
+}
+
+// contains checks if a slice contains a string
+func contains(slice []string, str string) bool {
+	for _, item := range slice {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
@@ -100,6 +100,12 @@ func GetAlpineSupport(extName string) *OSSupport {
 			InstallCmd:  "pecl install redis && docker-php-ext-enable redis",
 			PECLInstall: true,
 		},
+		"swoole": {
+			BuildDeps:   []string{},
+			RuntimeDeps: []string{"libstdc++"},
+			InstallCmd:  "pecl install swoole && docker-php-ext-enable swoole",
+			PECLInstall: true,
+		},
 		"imagick": {
 			BuildDeps:   []string{"imagemagick-dev"},
 			RuntimeDeps: []string{"imagemagick"},
@@ -124,6 +130,12 @@ func GetAlpineSupport(extName string) *OSSupport {
 			InstallCmd:  "pecl install xdebug && docker-php-ext-enable xdebug",
 			PECLInstall: true,
 		},
+		"pcov": {
+			BuildDeps:   []string{},
+			RuntimeDeps: []string{},
+			InstallCmd:  "pecl install pcov && docker-php-ext-enable pcov",
+			PECLInstall: true,
+		},
 		"apcu": {
 			BuildDeps:   []string{},
 			RuntimeDeps: []string{},
@@ -0,0 +1,106 @@
+package extensions
+
+import "strings"
+
+// Installers a base image can provide
+const (
+	InstallerAPK          = "apk"
+	InstallerAPT          = "apt-get"
+	InstallerDockerPHPExt = "docker-php-ext-install"
+	InstallerPECL         = "pecl"
+)
+
+// osFamilies maps BASE_OS values to the OS type whose package names they use
+var osFamilies = map[string]string{
+	"alpine": "alpine",
+	"debian": "ubuntu",
+	"ubuntu": "ubuntu",
+}
+
+// packageManagers are the package managers of the OS types
+var packageManagers = map[string]string{
+	"alpine": InstallerAPK,
+	"ubuntu": InstallerAPT,
+}
+
+// BaseImageConfig replaces the official php base images, e.g. with a
+// mirror or a hardened base
+type BaseImageConfig struct {
+	Image        string `json:"image,omitempty"`         // base image of the final stage
+	BuilderImage string `json:"builder_image,omitempty"` // base image of the builder stage
+	Registry     string `json:"registry,omitempty"`      // prefix of the default images, e.g. mirror.example.com/library
+	Digest       string `json:"digest,omitempty"`        // sha256 digest the final base image is pinned to
+	OS           string `json:"os,omitempty"`            // OS family of Image: alpine or debian
+	// Installers lists the tools Image provides; nil assumes those of the official images
+	Installers []string `json:"installers,omitempty"`
+}
+
+// GetInstallers returns the installers BASE_INSTALLERS accepts
+func GetInstallers() []string {
+	return []string{InstallerAPK, InstallerAPT, InstallerDockerPHPExt, InstallerPECL}
+}
+
+// GetOSFamilies returns the OS families BASE_OS accepts
+func GetOSFamilies() []string {
+	return []string{"alpine", "debian"}
+}
+
+// OSTypeOfFamily returns the OS type of an OS family
+func OSTypeOfFamily(family string) (string, bool) {
+	osType, ok := osFamilies[family]
+	return osType, ok
+}
+
+// GetPackageManager returns the package manager of osType
+func GetPackageManager(osType string) string {
+	return packageManagers[osType]
+}
+
+// HasInstaller reports whether the final base image provides installer on osType
+func (b BaseImageConfig) HasInstaller(installer, osType string) bool {
+	if b.Installers == nil {
+		return installer == InstallerDockerPHPExt || installer == InstallerPECL || installer == packageManagers[osType]
+	}
+	for _, declared := range b.Installers {
+		if declared == installer {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPackageManager reports whether the final base image can install packages on osType
+func (b BaseImageConfig) HasPackageManager(osType string) bool {
+	return b.HasInstaller(packageManagers[osType], osType)
+}
+
+// Resolve returns the final and builder base images for the official image
+// defaultImage. The builder uses the final base when it can compile
+// extensions, and the official image otherwise.
+func (b BaseImageConfig) Resolve(defaultImage, osType string) (base, builder string) {
+	official := defaultImage
+	if b.Registry != "" {
+		official = strings.TrimSuffix(b.Registry, "/") + "/" + defaultImage
+	}
+
+	base = official
+	if b.Image != "" {
+		base = b.Image
+	}
+	builder = b.BuilderImage
+	if builder == "" {
+		builder = official
+		if b.HasInstaller(InstallerDockerPHPExt, osType) {
+			builder = base
+		}
+	}
+
+	if b.Digest != "" {
+		pinned := base + "@" + b.Digest
+		if builder == base {
+			builder = pinned
+		}
+		base = pinned
+	}
+	return base, builder
+}
@@ -0,0 +1,26 @@
+package extensions
+
+// builtinModules are compiled into every official php image (7.4 - 8.3)
+var builtinModules = []string{
+	"Core", "ctype", "curl", "date", "dom", "fileinfo", "filter", "hash",
+	"iconv", "json", "libxml", "mbstring", "mysqlnd", "openssl", "pcre",
+	"PDO", "pdo_sqlite", "Phar", "posix", "readline", "Reflection",
+	"session", "SimpleXML", "sodium", "SPL", "sqlite3", "standard",
+	"tokenizer", "xml", "xmlreader", "xmlwriter", "zlib",
+}
+
+// GetBuiltinModules returns the modules loaded by default in official php images
+func GetBuiltinModules() []string {
+	modules := make([]string, len(builtinModules))
+	copy(modules, builtinModules)
+	return modules
+}
+
+// GetModuleName returns the name an extension reports in `php -m`
+func GetModuleName(extName string) string {
+	ext, exists := GetExtension(extName)
+	if !exists || ext.ModuleName == "" {
+		return extName
+	}
+	return ext.ModuleName
+}
@@ -0,0 +1,80 @@
+package extensions
+
+// Healthcheck defaults, rendered when not configured
+const (
+	DefaultHealthcheckInterval    = "30s"
+	DefaultHealthcheckTimeout     = "5s"
+	DefaultHealthcheckStartPeriod = "10s"
+	DefaultHealthcheckRetries     = "3"
+	DefaultHealthcheckPath        = "/"
+)
+
+// HealthcheckConfig configures the HEALTHCHECK of generated images
+type HealthcheckConfig struct {
+	Enable      *bool  `json:"enable,omitempty"`  // HEALTHCHECK=true/false; unset enables it when Command is set
+	Command     string `json:"command,omitempty"` // replaces the probe of the image type
+	Path        string `json:"path,omitempty"`    // HTTP path probed in apache and nginx-fpm images
+	Interval    string `json:"interval,omitempty"`
+	Timeout     string `json:"timeout,omitempty"`
+	StartPeriod string `json:"start_period,omitempty"`
+	Retries     string `json:"retries,omitempty"`
+}
+
+// IsEnabled reports whether a HEALTHCHECK is rendered
+func (h HealthcheckConfig) IsEnabled() bool {
+	if h.Enable != nil {
+		return *h.Enable
+	}
+	return h.Command != ""
+}
+
+// WithDefaults returns the config with unset options replaced by the defaults
+func (h HealthcheckConfig) WithDefaults() HealthcheckConfig {
+	if h.Path == "" {
+		h.Path = DefaultHealthcheckPath
+	}
+	if h.Interval == "" {
+		h.Interval = DefaultHealthcheckInterval
+	}
+	if h.Timeout == "" {
+		h.Timeout = DefaultHealthcheckTimeout
+	}
+	if h.StartPeriod == "" {
+		h.StartPeriod = DefaultHealthcheckStartPeriod
+	}
+	if h.Retries == "" {
+		h.Retries = DefaultHealthcheckRetries
+	}
+	return h
+}
+
+// healthcheckPackages are the runtime packages the default probe of an image type needs, by OS
+var healthcheckPackages = map[string]map[string][]string{
+	"fpm": {
+		"alpine": {"fcgi"},
+		"ubuntu": {"libfcgi-bin"},
+	},
+	"apache": {
+		"alpine": {"curl"},
+		"ubuntu": {"curl"},
+	},
+	"nginx-fpm": {
+		"alpine": {"curl"},
+		"ubuntu": {"curl"},
+	},
+	"frankenphp": {
+		"alpine": {"curl"},
+		"ubuntu": {"curl"},
+	},
+}
+
+// GetHealthcheckPackages returns the packages needed by the default probe of imageType on osType
+func GetHealthcheckPackages(imageType, osType string) []string {
+	return healthcheckPackages[imageType][osType]
+}
+
+// HasDefaultHealthcheck reports whether vess can probe imageType without a HEALTHCHECK_CMD
+func HasDefaultHealthcheck(imageType string) bool {
+	_, ok := healthcheckPackages[imageType]
+	return ok
+}
@@ -0,0 +1,74 @@
+package extensions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Hooks are Dockerfile instructions inserted at fixed points of generated Dockerfiles
+type Hooks struct {
+	PreBuildDeps    []string `json:"pre_build_deps,omitempty"`    // builder stage, before build dependencies
+	PostExtensions  []string `json:"post_extensions,omitempty"`   // builder stage, after the extensions are installed
+	FinalPreRuntime []string `json:"final_pre_runtime,omitempty"` // final stage, before runtime dependencies
+	FinalPostCopy   []string `json:"final_post_copy,omitempty"`   // final stage, after the extensions are copied in
+	PreCmd          []string `json:"pre_cmd,omitempty"`           // final stage, before WORKDIR and CMD
+}
+
+// hookKeys maps config keys to hook lists
+var hookKeys = map[string]func(h *Hooks) *[]string{
+	"HOOK_PRE_BUILD_DEPS":    func(h *Hooks) *[]string { return &h.PreBuildDeps },
+	"HOOK_POST_EXTENSIONS":   func(h *Hooks) *[]string { return &h.PostExtensions },
+	"HOOK_FINAL_PRE_RUNTIME": func(h *Hooks) *[]string { return &h.FinalPreRuntime },
+	"HOOK_FINAL_POST_COPY":   func(h *Hooks) *[]string { return &h.FinalPostCopy },
+	"HOOK_PRE_CMD":           func(h *Hooks) *[]string { return &h.PreCmd },
+}
+
+// hookInstructions are the Dockerfile instructions allowed in hooks. FROM is
+// excluded because it would split the generated stages.
+var hookInstructions = []string{
+	"ADD", "ARG", "COPY", "ENV", "EXPOSE", "HEALTHCHECK", "LABEL", "ONBUILD",
+	"RUN", "SHELL", "STOPSIGNAL", "USER", "VOLUME", "WORKDIR",
+}
+
+// GetHookKeys returns the config keys of all hooks
+func GetHookKeys() []string {
+	keys := make([]string, 0, len(hookKeys))
+	for key := range hookKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Add appends a Dockerfile instruction to the hook configured by key
+func (h *Hooks) Add(key, instruction string) error {
+	list, ok := hookKeys[key]
+	if !ok {
+		return fmt.Errorf("unknown hook %s (valid hooks: %s)", key, strings.Join(GetHookKeys(), ", "))
+	}
+
+	fields := strings.Fields(instruction)
+	if len(fields) < 2 {
+		return fmt.Errorf("expected a Dockerfile instruction, got %q", instruction)
+	}
+	name := strings.ToUpper(fields[0])
+	found := false
+	for _, allowed := range hookInstructions {
+		if name == allowed {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("instruction %s is not allowed in hooks", fields[0])
+	}
+
+	*list(h) = append(*list(h), instruction)
+	return nil
+}
+
+// IsEmpty reports whether no hook is configured
+func (h Hooks) IsEmpty() bool {
+	return len(h.PreBuildDeps)+len(h.PostExtensions)+len(h.FinalPreRuntime)+len(h.FinalPostCopy)+len(h.PreCmd) == 0
+}
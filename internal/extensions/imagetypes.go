@@ -0,0 +1,178 @@
+package extensions
+
+import "fmt"
+
+// ImageType describes a kind of image vess generates
+type ImageType struct {
+	Name string
+	// Base is the base image reference with %s for the PHP version; alpine
+	// images append -alpine
+	Base string
+	// OSTypes are the OS types the base image is published for
+	OSTypes []string
+	// PHPVersions limits the PHP versions the base image is published for (empty: all)
+	PHPVersions []string
+	// ZTS is set for thread-safe PHP builds, which cannot load modules built for the official images
+	ZTS bool
+	// WorkDir is the working directory of the final stage
+	WorkDir string
+	// Command is the default CMD; types with several programs have none
+	Command        []string
+	CommandComment string
+	Port           string
+	PortComment    string
+	// Programs are the server processes of the type when run under a process manager
+	Programs []*Program
+	// RequiresProcessManager is set for types running more than one server process
+	RequiresProcessManager bool
+	// Packages are the runtime packages the type needs, by OS
+	Packages map[string][]string
+	// RequiredExtensions must be listed in PHP_EXTENSIONS
+	RequiredExtensions []string
+}
+
+// DefaultWorkDir is the working directory of the official php images
+const DefaultWorkDir = "/var/www/html"
+
+// FrankenPHPCaddyfile is the Caddyfile shipped with the FrankenPHP images
+const FrankenPHPCaddyfile = "/etc/caddy/Caddyfile"
+
+// imageTypes lists the supported image types in the order they are presented
+var imageTypes = []*ImageType{
+	{
+		Name:           "cli",
+		Base:           "php:%s-cli",
+		OSTypes:        []string{"alpine", "ubuntu"},
+		WorkDir:        DefaultWorkDir,
+		Command:        []string{"php", "-a"},
+		CommandComment: "CLI mode - interactive shell",
+	},
+	{
+		Name:        "fpm",
+		Base:        "php:%s-fpm",
+		OSTypes:     []string{"alpine", "ubuntu"},
+		WorkDir:     DefaultWorkDir,
+		Command:     []string{"php-fpm"},
+		Port:        "9000",
+		PortComment: "Expose PHP-FPM port",
+		Programs:    []*Program{{Name: "php-fpm", Command: "php-fpm"}},
+	},
+	{
+		Name: "apache",
+		Base: "php:%s-apache",
+		// Docker Hub does not provide php:*-apache-alpine images
+		OSTypes:     []string{"ubuntu"},
+		WorkDir:     DefaultWorkDir,
+		Command:     []string{"apache2-foreground"},
+		Port:        "80",
+		PortComment: "Expose Apache port",
+		Programs:    []*Program{{Name: "apache", Command: "apache2-foreground"}},
+	},
+	{
+		Name:        "nginx-fpm",
+		Base:        "php:%s-fpm",
+		OSTypes:     []string{"alpine", "ubuntu"},
+		WorkDir:     DefaultWorkDir,
+		Port:        "80",
+		PortComment: "Expose nginx port",
+		Programs: []*Program{
+			{Name: "php-fpm", Command: "php-fpm"},
+			{Name: "nginx", Command: "nginx -g 'daemon off;'"},
+		},
+		RequiresProcessManager: true,
+		Packages: map[string][]string{
+			"alpine": {"nginx"},
+			"ubuntu": {"nginx"},
+		},
+	},
+	{
+		Name: "frankenphp",
+		// FrankenPHP 1.x images are built on the thread-safe official images
+		Base:           "dunglas/frankenphp:1-php%s",
+		OSTypes:        []string{"alpine", "ubuntu"},
+		PHPVersions:    []string{"8.2", "8.3"},
+		ZTS:            true,
+		WorkDir:        "/app",
+		Command:        []string{"frankenphp", "run", "--config", FrankenPHPCaddyfile},
+		CommandComment: "Serve /app/public with FrankenPHP (Caddy)",
+		Port:           "80 443 443/udp",
+		PortComment:    "Expose HTTP, HTTPS and HTTP/3 ports",
+		Programs:       []*Program{{Name: "frankenphp", Command: "frankenphp run --config " + FrankenPHPCaddyfile}},
+	},
+	{
+		Name:           "roadrunner",
+		Base:           "php:%s-cli",
+		OSTypes:        []string{"alpine", "ubuntu"},
+		WorkDir:        DefaultWorkDir,
+		Command:        []string{"rr", "serve", "-c", ".rr.yaml"},
+		CommandComment: "Start RoadRunner with .rr.yaml from the working directory",
+		Port:           "8080",
+		PortComment:    "Expose RoadRunner HTTP port",
+		Programs:       []*Program{{Name: "roadrunner", Command: "rr serve -c " + DefaultWorkDir + "/.rr.yaml -w " + DefaultWorkDir}},
+		// The goridge relay of the PHP workers needs ext-sockets
+		RequiredExtensions: []string{"sockets"},
+	},
+	{
+		Name:               "swoole",
+		Base:               "php:%s-cli",
+		OSTypes:            []string{"alpine", "ubuntu"},
+		WorkDir:            DefaultWorkDir,
+		Command:            []string{"php", "server.php"},
+		CommandComment:     "Start the Swoole server script; set CMD for e.g. php artisan octane:start",
+		Port:               "9501",
+		PortComment:        "Expose Swoole HTTP port",
+		Programs:           []*Program{{Name: "swoole", Command: "php " + DefaultWorkDir + "/server.php"}},
+		RequiredExtensions: []string{"swoole"},
+	},
+}
+
+// GetImageType returns the image type called name
+func GetImageType(name string) (*ImageType, bool) {
+	for _, t := range imageTypes {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// GetImageTypeNames returns the names of all image types
+func GetImageTypeNames() []string {
+	names := make([]string, 0, len(imageTypes))
+	for _, t := range imageTypes {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// SupportsOS reports whether the base image of the type is published for osType
+func (t *ImageType) SupportsOS(osType string) bool {
+	for _, supported := range t.OSTypes {
+		if supported == osType {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsPHPVersion reports whether the base image of the type is published for phpVersion
+func (t *ImageType) SupportsPHPVersion(phpVersion string) bool {
+	if len(t.PHPVersions) == 0 {
+		return true
+	}
+	for _, supported := range t.PHPVersions {
+		if supported == phpVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// BaseImage returns the image the type builds on
+func (t *ImageType) BaseImage(osType, phpVersion string) string {
+	image := fmt.Sprintf(t.Base, phpVersion)
+	if osType == "alpine" {
+		image += "-alpine"
+	}
+	return image
+}
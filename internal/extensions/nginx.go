@@ -0,0 +1,34 @@
+package extensions
+
+// nginx defaults for the nginx-fpm image type
+const (
+	DefaultNginxDocumentRoot       = "/var/www/html"
+	DefaultNginxClientMaxBodySize  = "8m"
+	DefaultNginxFastCGIReadTimeout = "60s"
+)
+
+// NginxConfig configures the server block of nginx-fpm images
+type NginxConfig struct {
+	DocumentRoot       string `json:"document_root,omitempty"`
+	ClientMaxBodySize  string `json:"client_max_body_size,omitempty"`
+	FastCGIReadTimeout string `json:"fastcgi_read_timeout,omitempty"`
+}
+
+// WithDefaults returns the config with unset options replaced by the defaults
+func (n NginxConfig) WithDefaults() NginxConfig {
+	if n.DocumentRoot == "" {
+		n.DocumentRoot = DefaultNginxDocumentRoot
+	}
+	if n.ClientMaxBodySize == "" {
+		n.ClientMaxBodySize = DefaultNginxClientMaxBodySize
+	}
+	if n.FastCGIReadTimeout == "" {
+		n.FastCGIReadTimeout = DefaultNginxFastCGIReadTimeout
+	}
+	return n
+}
+
+// IsSet reports whether any nginx option was configured
+func (n NginxConfig) IsSet() bool {
+	return n != NginxConfig{}
+}
@@ -0,0 +1,82 @@
+package extensions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// packageNames maps common packages whose names differ between Alpine and
+// Debian/Ubuntu. Either name can be used in a config for both OS types.
+var packageNames = []struct {
+	alpine string
+	ubuntu string
+}{
+	{"build-base", "build-essential"},
+	{"curl-dev", "libcurl4-openssl-dev"},
+	{"font-dejavu", "fonts-dejavu"},
+	{"freetype-dev", "libfreetype6-dev"},
+	{"gmp-dev", "libgmp-dev"},
+	{"icu-dev", "libicu-dev"},
+	{"imagemagick-dev", "libmagickwand-dev"},
+	{"libjpeg-turbo-dev", "libjpeg-dev"},
+	{"libpq", "libpq5"},
+	{"libxslt-dev", "libxslt1-dev"},
+	{"libzip", "libzip4"},
+	{"mariadb-client", "default-mysql-client"},
+	{"musl-locales", "locales"},
+	{"oniguruma-dev", "libonig-dev"},
+	{"openssl-dev", "libssl-dev"},
+	{"postgresql-dev", "libpq-dev"},
+	{"sqlite-dev", "libsqlite3-dev"},
+	{"zlib-dev", "zlib1g-dev"},
+}
+
+// packageNamePatterns are the package name rules of each OS, with an optional =version pin
+var packageNamePatterns = map[string]*regexp.Regexp{
+	"alpine": regexp.MustCompile(`^[a-z0-9][a-z0-9+._-]*(=[A-Za-z0-9+._~-]+)?$`),
+	"ubuntu": regexp.MustCompile(`^[a-z0-9][a-z0-9+.-]+(=[A-Za-z0-9+.:~-]+)?$`),
+}
+
+// MapPackage returns the name of pkg on osType, translating known names of
+// the other OS. Unknown names and version pins are returned unchanged.
+func MapPackage(pkg, osType string) string {
+	name, version, pinned := strings.Cut(pkg, "=")
+	for _, names := range packageNames {
+		if name != names.alpine && name != names.ubuntu {
+			continue
+		}
+		switch osType {
+		case "alpine":
+			name = names.alpine
+		case "ubuntu":
+			name = names.ubuntu
+		}
+		break
+	}
+	if pinned {
+		return name + "=" + version
+	}
+	return name
+}
+
+// ResolvePackages maps pkgs to their names on osType
+func ResolvePackages(pkgs []string, osType string) []string {
+	result := make([]string, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		result = append(result, MapPackage(pkg, osType))
+	}
+	return result
+}
+
+// ValidatePackageName checks that pkg is a valid package name on osType
+func ValidatePackageName(pkg, osType string) error {
+	pattern, ok := packageNamePatterns[osType]
+	if !ok {
+		return fmt.Errorf("unsupported OS: %s", osType)
+	}
+	if !pattern.MatchString(pkg) {
+		return fmt.Errorf("invalid %s package name '%s'", osType, pkg)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package extensions
+
+import (
+	"strconv"
+	"strings"
+)
+
+// minPECLVersions lists, per PECL extension, the oldest release that builds
+// against each PHP version
+var minPECLVersions = map[string]map[string]string{
+	"redis":     {"7.4": "5.0.0", "8.0": "5.3.2", "8.1": "5.3.5", "8.2": "5.3.7", "8.3": "6.0.0"},
+	"swoole":    {"8.1": "4.8.0", "8.2": "5.0.2", "8.3": "5.1.0"},
+	"imagick":   {"7.4": "3.4.4", "8.0": "3.5.0", "8.1": "3.6.0", "8.2": "3.7.0", "8.3": "3.7.0"},
+	"memcached": {"7.4": "3.1.4", "8.0": "3.1.5", "8.1": "3.2.0", "8.2": "3.2.0", "8.3": "3.2.0"},
+	"mongodb":   {"7.4": "1.6.0", "8.0": "1.9.0", "8.1": "1.11.0", "8.2": "1.15.0", "8.3": "1.17.0"},
+	"xdebug":    {"7.4": "2.8.0", "8.0": "3.0.0", "8.1": "3.1.0", "8.2": "3.2.0", "8.3": "3.3.0"},
+	"pcov":      {"7.4": "1.0.6", "8.0": "1.0.7", "8.1": "1.0.10", "8.2": "1.0.11", "8.3": "1.0.11"},
+	"apcu":      {"7.4": "5.1.18", "8.0": "5.1.19", "8.1": "5.1.21", "8.2": "5.1.22", "8.3": "5.1.23"},
+}
+
+// alternatives suggests replacements for extensions on the PHP versions
+// they do not support
+var alternatives = map[string][]string{
+	"xmlrpc": {
+		"phpxmlrpc/phpxmlrpc, a pure-PHP Composer package with the same protocol support",
+		"soap, if the remote service also offers a SOAP endpoint",
+	},
+	"swoole": {
+		"roadrunner or frankenphp image types for long-running PHP workers",
+	},
+}
+
+// MinPECLVersion returns the oldest release of a PECL extension that builds
+// against phpVersion, or "" if it is not known
+func MinPECLVersion(extName, phpVersion string) string {
+	return minPECLVersions[extName][phpVersion]
+}
+
+// GetAlternatives returns replacements suggested for an extension on PHP
+// versions it does not support
+func GetAlternatives(extName string) []string {
+	return alternatives[extName]
+}
+
+// CompareVersions compares dotted version numbers such as 5.3.7 or 8.3,
+// returning -1, 0 or 1. A pre-release suffix (6.0.0RC1) sorts before the
+// release.
+func CompareVersions(a, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		aNum, aSuffix := versionPart(aParts, i)
+		bNum, bSuffix := versionPart(bParts, i)
+		switch {
+		case aNum != bNum:
+			return compareInts(aNum, bNum)
+		case aSuffix != bSuffix:
+			// A release has no suffix and sorts after its pre-releases
+			if aSuffix == "" {
+				return 1
+			}
+			if bSuffix == "" {
+				return -1
+			}
+			return strings.Compare(strings.ToLower(aSuffix), strings.ToLower(bSuffix))
+		}
+	}
+	return 0
+}
+
+// versionPart splits the i-th component of a version into its number and suffix
+func versionPart(parts []string, i int) (int, string) {
+	if i >= len(parts) {
+		return 0, ""
+	}
+	digits := len(parts[i]) - len(strings.TrimLeft(parts[i], "0123456789"))
+	num, _ := strconv.Atoi(parts[i][:digits])
+	return num, parts[i][digits:]
+}
+
+// compareInts returns -1, 0 or 1
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
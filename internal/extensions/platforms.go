@@ -0,0 +1,37 @@
+package extensions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// basePlatforms lists the platforms published for the official php images
+var basePlatforms = map[string][]string{
+	"alpine": {"linux/amd64", "linux/arm/v6", "linux/arm/v7", "linux/arm64", "linux/386", "linux/ppc64le", "linux/riscv64", "linux/s390x"},
+	"ubuntu": {"linux/amd64", "linux/arm/v5", "linux/arm/v7", "linux/arm64", "linux/386", "linux/mips64le", "linux/ppc64le", "linux/s390x"},
+}
+
+// NormalizePlatform canonicalizes a platform string such as "linux/arm64/v8"
+func NormalizePlatform(platform string) (string, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(platform)), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("invalid platform: %s (expected os/arch[/variant], e.g. linux/arm64)", platform)
+	}
+
+	switch parts[1] {
+	case "x86_64", "x86-64":
+		parts[1] = "amd64"
+	case "aarch64":
+		parts[1] = "arm64"
+	}
+	if parts[1] == "arm64" && len(parts) == 3 && parts[2] == "v8" {
+		parts = parts[:2]
+	}
+
+	return strings.Join(parts, "/"), nil
+}
+
+// GetBasePlatforms returns the platforms available for an OS's base images
+func GetBasePlatforms(osType string) []string {
+	return basePlatforms[osType]
+}
@@ -0,0 +1,122 @@
+package extensions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Process managers running several programs in one container
+const (
+	ProcessManagerSupervisord = "supervisord"
+	ProcessManagerS6          = "s6"
+)
+
+// InitTini runs the container command under tini, which forwards signals and reaps zombies
+const InitTini = "tini"
+
+// Program is a process run by the process manager
+type Program struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+}
+
+// ProcessConfig configures how the container starts
+type ProcessConfig struct {
+	Command    string     `json:"command,omitempty"`    // CMD, e.g. php artisan horizon
+	Entrypoint string     `json:"entrypoint,omitempty"` // ENTRYPOINT replacing docker-php-entrypoint
+	Manager    string     `json:"manager,omitempty"`    // supervisord or s6
+	Init       string     `json:"init,omitempty"`       // tini
+	Programs   []*Program `json:"programs,omitempty"`   // extra programs run by the manager
+}
+
+// programNamePattern restricts program names to what supervisord and s6 accept
+var programNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ParseProgram parses a name:command program definition
+func ParseProgram(value string) (*Program, error) {
+	name, command, ok := strings.Cut(value, ":")
+	name, command = strings.TrimSpace(name), strings.TrimSpace(command)
+	if !ok || name == "" || command == "" {
+		return nil, fmt.Errorf("expected name:command, got %q", value)
+	}
+	if !programNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid program name '%s'", name)
+	}
+	return &Program{Name: name, Command: command}, nil
+}
+
+// SplitCommand splits a command line into words, honouring single quotes,
+// double quotes and backslash escapes like a POSIX shell
+func SplitCommand(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else if r == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[i+1]) {
+				i++
+				word.WriteRune(runes[i])
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\' && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, command)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// processPackages are the runtime packages of process managers and init systems, by OS.
+// s6-overlay is downloaded from its releases instead.
+var processPackages = map[string]map[string][]string{
+	ProcessManagerSupervisord: {
+		"alpine": {"supervisor"},
+		"ubuntu": {"supervisor"},
+	},
+	InitTini: {
+		"alpine": {"tini"},
+		"ubuntu": {"tini"},
+	},
+}
+
+// GetProcessPackages returns the packages the process settings of cfg need on osType
+func GetProcessPackages(cfg ProcessConfig, osType string) []string {
+	var pkgs []string
+	pkgs = append(pkgs, processPackages[cfg.Manager][osType]...)
+	pkgs = append(pkgs, processPackages[cfg.Init][osType]...)
+	return pkgs
+}
@@ -61,7 +61,8 @@ var registry = map[string]*Extension{
 			"alpine": GetAlpineSupport("opcache"),
 			"ubuntu": GetUbuntuSupport("opcache"),
 		},
-		Conflicts: []string{},
+		Conflicts:  []string{},
+		ModuleName: "Zend OPcache",
 	},
 	"zip": {
 		Name:        "zip",
@@ -164,6 +165,16 @@ var registry = map[string]*Extension{
 		},
 		Conflicts: []string{},
 	},
+	"swoole": {
+		Name:        "swoole",
+		Description: "Swoole Coroutine Server Extension (PECL)",
+		PHPVersions: []string{"8.1", "8.2", "8.3"},
+		OSSupport: map[string]*OSSupport{
+			"alpine": GetAlpineSupport("swoole"),
+			"ubuntu": GetUbuntuSupport("swoole"),
+		},
+		Conflicts: []string{},
+	},
 	"imagick": {
 		Name:        "imagick",
 		Description: "ImageMagick Extension (PECL)",
@@ -206,6 +217,16 @@ var registry = map[string]*Extension{
 		},
 		Conflicts: []string{},
 	},
+	"pcov": {
+		Name:        "pcov",
+		Description: "PCOV Code Coverage Driver (PECL)",
+		PHPVersions: []string{"7.4", "8.0", "8.1", "8.2", "8.3"},
+		OSSupport: map[string]*OSSupport{
+			"alpine": GetAlpineSupport("pcov"),
+			"ubuntu": GetUbuntuSupport("pcov"),
+		},
+		Conflicts: []string{},
+	},
 	"apcu": {
 		Name:        "apcu",
 		Description: "APCu Cache Extension (PECL)",
@@ -4,10 +4,11 @@ package extensions
 type Extension struct {
 	Name          string                `json:"name"`
 	Description   string                `json:"description"`
-	PHPVersions   []string              `json:"php_versions"`   // Supported PHP versions
-	OSSupport     map[string]*OSSupport `json:"os_support"`     // OS-specific installation info
-	Conflicts     []string              `json:"conflicts"`      // Conflicting extensions
-	ConfigureArgs []string              `json:"configure_args"` // Additional configure arguments
+	PHPVersions   []string              `json:"php_versions"`          // Supported PHP versions
+	OSSupport     map[string]*OSSupport `json:"os_support"`            // OS-specific installation info
+	Conflicts     []string              `json:"conflicts"`             // Conflicting extensions
+	ConfigureArgs []string              `json:"configure_args"`        // Additional configure arguments
+	ModuleName    string                `json:"module_name,omitempty"` // Name reported by `php -m`, if different
 }
 
 // OSSupport contains OS-specific installation information
@@ -20,8 +21,42 @@ type OSSupport struct {
 
 // Config represents the parsed configuration
 type Config struct {
-	Extensions []string          `json:"extensions"`
-	Metadata   map[string]string `json:"metadata"`
+	Source        string            `json:"source,omitempty"` // Path of the file the config was read from
+	Extensions    []string          `json:"extensions"`
+	DevExtensions []string          `json:"dev_extensions,omitempty"` // Extensions only installed in the dev target
+	PECLVersions  map[string]string `json:"pecl_versions,omitempty"`  // Pinned PECL package versions by extension
+	Ini           []string          `json:"ini,omitempty"`            // php.ini settings (name=value) for all targets
+	DevIni        []string          `json:"dev_ini,omitempty"`        // php.ini settings only applied in the dev target
+	// OS packages unrelated to extensions; build packages are removed after compiling
+	SystemPackages      []string          `json:"system_packages,omitempty"`
+	SystemBuildPackages []string          `json:"system_build_packages,omitempty"`
+	Xdebug              XdebugConfig      `json:"xdebug"`
+	Healthcheck         HealthcheckConfig `json:"healthcheck"`
+	Process             ProcessConfig     `json:"process"`
+	Nginx               NginxConfig       `json:"nginx"`
+	Base                BaseImageConfig   `json:"base"`
+	Hooks               Hooks             `json:"hooks"`
+	TemplatesDir        string            `json:"templates_dir,omitempty"` // custom templates, relative paths resolved against the env file
+	Metadata            map[string]string `json:"metadata"`
+}
+
+// Build targets of generated Dockerfiles with dev-only settings
+const (
+	TargetDev  = "dev"
+	TargetProd = "prod"
+)
+
+// HasDevTarget reports whether the config has dev-only extensions or settings
+func (c *Config) HasDevTarget() bool {
+	return len(c.DevExtensions) > 0 || len(c.DevIni) > 0
+}
+
+// TargetExtensions returns the extensions installed in target
+func (c *Config) TargetExtensions(target string) []string {
+	if target != TargetDev {
+		return c.Extensions
+	}
+	return append(append([]string{}, c.Extensions...), c.DevExtensions...)
 }
 
 // ValidationError represents a validation error
@@ -100,6 +100,12 @@ func GetUbuntuSupport(extName string) *OSSupport {
 			InstallCmd:  "pecl install redis && docker-php-ext-enable redis",
 			PECLInstall: true,
 		},
+		"swoole": {
+			BuildDeps:   []string{},
+			RuntimeDeps: []string{},
+			InstallCmd:  "pecl install swoole && docker-php-ext-enable swoole",
+			PECLInstall: true,
+		},
 		"imagick": {
 			BuildDeps:   []string{"libmagickwand-dev"},
 			RuntimeDeps: []string{"libmagickwand-6.q16-6"},
@@ -124,6 +130,12 @@ func GetUbuntuSupport(extName string) *OSSupport {
 			InstallCmd:  "pecl install xdebug && docker-php-ext-enable xdebug",
 			PECLInstall: true,
 		},
+		"pcov": {
+			BuildDeps:   []string{},
+			RuntimeDeps: []string{},
+			InstallCmd:  "pecl install pcov && docker-php-ext-enable pcov",
+			PECLInstall: true,
+		},
 		"apcu": {
 			BuildDeps:   []string{},
 			RuntimeDeps: []string{},
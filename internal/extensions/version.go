@@ -0,0 +1,18 @@
+package extensions
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+)
+
+// RegistryVersion returns a fingerprint of the extension registry, which
+// changes whenever an extension's versions, packages or commands change
+func RegistryVersion() string {
+	// Maps marshal with sorted keys, so the encoding is stable
+	data, err := json.Marshal([]interface{}{registry, minPECLVersions, alternatives})
+	if err != nil {
+		return "unknown"
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))[:19]
+}
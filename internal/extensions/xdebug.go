@@ -0,0 +1,66 @@
+package extensions
+
+import "strings"
+
+// Xdebug defaults for containers. host.docker.internal resolves to the host on
+// Docker Desktop and Podman; Linux engines need --add-host=host.docker.internal:host-gateway.
+const (
+	DefaultXdebugMode       = "debug"
+	DefaultXdebugClientHost = "host.docker.internal"
+	DefaultXdebugClientPort = "9003"
+)
+
+// xdebugModes are the XDEBUG_MODE values accepted in configs
+var xdebugModes = []string{"off", "debug", "coverage", "profile", "develop", "trace"}
+
+// XdebugConfig configures xdebug when it is installed
+type XdebugConfig struct {
+	Mode       string `json:"mode,omitempty"` // comma-separated xdebug.mode, e.g. debug,coverage
+	ClientHost string `json:"client_host,omitempty"`
+	ClientPort string `json:"client_port,omitempty"`
+}
+
+// IsSet reports whether any xdebug option was configured
+func (x XdebugConfig) IsSet() bool {
+	return x.Mode != "" || x.ClientHost != "" || x.ClientPort != ""
+}
+
+// WithDefaults returns the config with unset options replaced by the defaults
+func (x XdebugConfig) WithDefaults() XdebugConfig {
+	if x.Mode == "" {
+		x.Mode = DefaultXdebugMode
+	}
+	if x.ClientHost == "" {
+		x.ClientHost = DefaultXdebugClientHost
+	}
+	if x.ClientPort == "" {
+		x.ClientPort = DefaultXdebugClientPort
+	}
+	return x
+}
+
+// GetXdebugModes returns the accepted xdebug modes
+func GetXdebugModes() []string {
+	modes := make([]string, len(xdebugModes))
+	copy(modes, xdebugModes)
+	return modes
+}
+
+// InvalidXdebugModes returns the parts of mode that are not xdebug modes
+func InvalidXdebugModes(mode string) []string {
+	var invalid []string
+	for _, part := range strings.Split(mode, ",") {
+		part = strings.TrimSpace(part)
+		found := false
+		for _, m := range xdebugModes {
+			if part == m {
+				found = true
+				break
+			}
+		}
+		if !found {
+			invalid = append(invalid, part)
+		}
+	}
+	return invalid
+}
@@ -2,6 +2,18 @@ package generator
 
 import (
 	"fmt"
+	"strings"
+
+	"vess/internal/cache"
+	"vess/internal/extensions"
+)
+
+// Rendering modes
+const (
+	// ModeCompat renders one RUN per extension and works with the classic builder
+	ModeCompat = "compat"
+	// ModeOptimized renders consolidated RUNs with BuildKit cache mounts
+	ModeOptimized = "optimized"
 )
 
 // Generator generates Dockerfiles
@@ -9,7 +21,15 @@ type Generator struct {
 	osType     string
 	phpVersion string
 	imageType  string
+	mode       string
 	engine     *TemplateEngine
+
+	cache  *cache.Cache
+	arch   string
+	cached []*cache.Entry
+
+	digests         map[string]string
+	packageVersions map[string]map[string]string
 }
 
 // New creates a new Dockerfile generator
@@ -19,17 +39,146 @@ func New(osType, phpVersion, imageType string) *Generator {
 		osType:     osType,
 		phpVersion: phpVersion,
 		imageType:  imageType,
+		mode:       ModeCompat,
 		engine:     engine,
 	}
 }
 
-// Generate generates a Dockerfile from extensions
-func (g *Generator) Generate(extNames []string) (string, error) {
+// SetMode selects the rendering mode (compat or optimized)
+func (g *Generator) SetMode(mode string) error {
+	switch mode {
+	case ModeCompat, ModeOptimized:
+		g.mode = mode
+		return nil
+	default:
+		return fmt.Errorf("unsupported mode: %s (must be one of: %s, %s)", mode, ModeCompat, ModeOptimized)
+	}
+}
+
+// SetTemplates layers the custom templates in dir over the embedded ones
+func (g *Generator) SetTemplates(dir string) error {
+	return g.engine.LoadOverrides(dir)
+}
+
+// SetCache makes Generate copy pre-built extensions for arch from c instead
+// of compiling them. The files are expected in .vess/ext next to the
+// Dockerfile; see CachedEntries.
+func (g *Generator) SetCache(c *cache.Cache, arch string) {
+	g.cache = c
+	g.arch = arch
+}
+
+// CachedEntries returns the cache entries used by the last Generate call.
+// They must be exported with cache.Export into CacheContextDir next to the Dockerfile.
+func (g *Generator) CachedEntries() []*cache.Entry {
+	return g.cached
+}
+
+// SetDigests pins the base images to the given digests, keyed by image
+// reference. Images without a digest are rendered unpinned.
+func (g *Generator) SetDigests(digests map[string]string) {
+	g.digests = digests
+}
+
+// Inputs are the external inputs a generated Dockerfile depends on
+type Inputs struct {
+	// Images are the unpinned references of the images the stages build from
+	Images []string
+	// Packages are the OS packages installed by the Dockerfile, keyed by the
+	// image reference they are installed on
+	Packages map[string][]string
+}
+
+// Inputs returns the base images and OS packages the Dockerfile for cfg uses
+func (g *Generator) Inputs(cfg *extensions.Config) (*Inputs, error) {
+	data, err := PrepareTemplateData(g.osType, g.phpVersion, g.imageType, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare template data: %w", err)
+	}
+
+	inputs := &Inputs{Packages: make(map[string][]string)}
+	for _, image := range []string{data.BaseImage, data.BuilderImage} {
+		if !strings.Contains(image, "@") && !containsString(inputs.Images, image) {
+			inputs.Images = append(inputs.Images, image)
+		}
+	}
+	if !data.HasPackageManager {
+		return inputs, nil
+	}
+
+	builderPackages, runtimePackages := data.BuildDeps, data.RuntimeDeps
+	if data.Dev != nil {
+		builderPackages = mergePackages(builderPackages, data.Dev.BuildDeps)
+		runtimePackages = mergePackages(runtimePackages, data.Dev.RuntimeDeps)
+	}
+	inputs.Packages[data.BuilderImage] = mergePackages(inputs.Packages[data.BuilderImage], builderPackages)
+	inputs.Packages[data.BaseImage] = mergePackages(inputs.Packages[data.BaseImage], runtimePackages)
+	for image, packages := range inputs.Packages {
+		if len(packages) == 0 {
+			delete(inputs.Packages, image)
+		}
+	}
+	return inputs, nil
+}
+
+// SetPackageVersions pins the OS packages installed on each image, keyed by
+// image reference and package name. Packages without a version are
+// rendered unpinned.
+func (g *Generator) SetPackageVersions(versions map[string]map[string]string) {
+	g.packageVersions = versions
+}
+
+// pinPackages appends the versions set for the packages installed on image
+func (g *Generator) pinPackages(image string, packages []string) []string {
+	versions := g.packageVersions[image]
+	if len(versions) == 0 {
+		return packages
+	}
+	pinned := make([]string, len(packages))
+	for i, pkg := range packages {
+		pinned[i] = pkg
+		if version := versions[pkg]; version != "" {
+			pinned[i] = pkg + "=" + version
+		}
+	}
+	return pinned
+}
+
+// pin appends the digest set for image, if any
+func (g *Generator) pin(image string) string {
+	if digest := g.digests[image]; digest != "" && !strings.Contains(image, "@") {
+		return image + "@" + digest
+	}
+	return image
+}
+
+// CacheContextDir is the build context directory the generated Dockerfile copies cached extensions from
+const CacheContextDir = ".vess/ext"
+
+// Generate generates a Dockerfile from a parsed configuration
+func (g *Generator) Generate(cfg *extensions.Config) (string, error) {
 	// Prepare template data
-	data, err := PrepareTemplateData(g.osType, g.phpVersion, g.imageType, extNames)
+	data, err := PrepareTemplateData(g.osType, g.phpVersion, g.imageType, cfg)
 	if err != nil {
 		return "", fmt.Errorf("failed to prepare template data: %w", err)
 	}
+	data.BuildDeps = g.pinPackages(data.BuilderImage, data.BuildDeps)
+	data.RuntimeDeps = g.pinPackages(data.BaseImage, data.RuntimeDeps)
+	if data.Dev != nil {
+		data.Dev.BuildDeps = g.pinPackages(data.BuilderImage, data.Dev.BuildDeps)
+		data.Dev.RuntimeDeps = g.pinPackages(data.BaseImage, data.Dev.RuntimeDeps)
+	}
+	data.BaseImage, data.BuilderImage = g.pin(data.BaseImage), g.pin(data.BuilderImage)
+	if err := g.applyCache(cfg, data); err != nil {
+		return "", err
+	}
+	if g.mode == ModeOptimized {
+		data.Optimized = true
+		data.Install = PlanInstall(data.Extensions)
+		if data.Dev != nil {
+			data.Dev.Install = PlanInstall(data.Dev.Extensions)
+		}
+	}
 
 	// Select template based on OS
 	var templateName string
@@ -49,3 +198,38 @@ func (g *Generator) Generate(extNames []string) (string, error) {
 
 	return content, nil
 }
+
+// applyCache looks up every extension in the cache and switches hits from
+// compiling to copying
+func (g *Generator) applyCache(cfg *extensions.Config, data *TemplateData) error {
+	g.cached = nil
+	if g.cache == nil {
+		return nil
+	}
+	// Cached modules are built against the non thread-safe official images
+	if typ, ok := extensions.GetImageType(g.imageType); ok && typ.ZTS {
+		return nil
+	}
+
+	var names []string
+	for _, ext := range data.Extensions {
+		entry, err := g.cache.Lookup(ext.Name, cfg.PECLVersions[ext.Name], g.phpVersion, g.osType, g.arch)
+		if err != nil {
+			return fmt.Errorf("failed to read extension cache: %w", err)
+		}
+		if entry == nil {
+			continue
+		}
+		// Modules built against another extension_dir cannot be mixed
+		if len(g.cached) > 0 && entry.ExtensionDir != g.cached[0].ExtensionDir {
+			continue
+		}
+		g.cached = append(g.cached, entry)
+		names = append(names, ext.Name)
+	}
+
+	if len(g.cached) > 0 {
+		data.UseCached(names, g.cached[0].ExtensionDir)
+	}
+	return nil
+}
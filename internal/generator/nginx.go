@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"fmt"
+
+	"vess/internal/extensions"
+)
+
+// nginxConfPaths are the server block locations of the nginx packages by OS
+var nginxConfPaths = map[string]string{
+	"alpine": "/etc/nginx/http.d/default.conf",
+	"ubuntu": "/etc/nginx/conf.d/default.conf",
+}
+
+// nginxDefaultSites are the default sites removed so the generated server block is used
+var nginxDefaultSites = map[string][]string{
+	"ubuntu": {"/etc/nginx/sites-enabled/default"},
+}
+
+// NginxFastCGIPass is the php-fpm address nginx forwards PHP requests to
+const NginxFastCGIPass = "127.0.0.1:9000"
+
+// NginxData holds the server block of nginx-fpm images
+type NginxData struct {
+	ConfPath    string
+	RemoveFiles []string
+	// Conf holds the lines of the server block
+	Conf []string
+}
+
+// newNginxData returns the server block for cfg on osType. A non-empty
+// pingPath is forwarded to php-fpm for local health checks.
+func newNginxData(cfg extensions.NginxConfig, osType, pingPath string) *NginxData {
+	cfg = cfg.WithDefaults()
+	conf := []string{
+		"server {",
+		"    listen 80 default_server;",
+		"    root " + cfg.DocumentRoot + ";",
+		"    index index.php index.html;",
+		"    client_max_body_size " + cfg.ClientMaxBodySize + ";",
+		"    access_log /dev/stdout;",
+		"    error_log /dev/stderr;",
+		"",
+		"    location / {",
+		"        try_files $uri $uri/ /index.php?$query_string;",
+		"    }",
+		"",
+		`    location ~ \.php$ {`,
+		"        try_files $uri =404;",
+		`        fastcgi_split_path_info ^(.+\.php)(/.+)$;`,
+		"        fastcgi_pass " + NginxFastCGIPass + ";",
+		"        fastcgi_index index.php;",
+		"        include fastcgi_params;",
+		"        fastcgi_param SCRIPT_FILENAME $document_root$fastcgi_script_name;",
+		"        fastcgi_param PATH_INFO $fastcgi_path_info;",
+		"        fastcgi_read_timeout " + cfg.FastCGIReadTimeout + ";",
+		"    }",
+	}
+	if pingPath != "" {
+		conf = append(conf,
+			"",
+			fmt.Sprintf("    location = %s {", pingPath),
+			"        allow 127.0.0.1;",
+			"        deny all;",
+			"        access_log off;",
+			"        include fastcgi_params;",
+			"        fastcgi_param SCRIPT_FILENAME $fastcgi_script_name;",
+			"        fastcgi_pass "+NginxFastCGIPass+";",
+			"    }",
+		)
+	}
+	conf = append(conf,
+		"",
+		`    location ~ /\.(?!well-known) {`,
+		"        deny all;",
+		"    }",
+		"}",
+	)
+
+	return &NginxData{
+		ConfPath:    nginxConfPaths[osType],
+		RemoveFiles: nginxDefaultSites[osType],
+		Conf:        conf,
+	}
+}
@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"vess/internal/extensions"
+)
+
+// SupervisordConfPath is where the generated supervisord configuration is written
+const SupervisordConfPath = "/usr/local/etc/supervisord.conf"
+
+// S6OverlayVersion is the s6-overlay release installed for PROCESS_MANAGER=s6
+const S6OverlayVersion = "3.2.0.2"
+
+// ProcessData describes how the final stage starts its processes
+type ProcessData struct {
+	Entrypoint []string // nil keeps the base image entrypoint
+	Cmd        []string // nil leaves CMD unset, e.g. under s6-overlay
+	CmdComment string
+	Manager    string
+	Programs   []*extensions.Program
+	// SupervisordConf holds the lines of the supervisord configuration
+	SupervisordConf []string
+	S6Version       string
+}
+
+// SupervisordConfPath returns where the supervisord configuration is written
+func (p *ProcessData) SupervisordConfPath() string {
+	return SupervisordConfPath
+}
+
+// newProcessData resolves the entrypoint, command and programs of imageType
+func newProcessData(cfg extensions.ProcessConfig, imageType *extensions.ImageType) (*ProcessData, error) {
+	cmd, err := extensions.SplitCommand(cfg.Command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CMD: %w", err)
+	}
+	entrypoint, err := extensions.SplitCommand(cfg.Entrypoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ENTRYPOINT: %w", err)
+	}
+
+	data := &ProcessData{Entrypoint: entrypoint}
+	manager := cfg.Manager
+	if manager == "" && imageType.RequiresProcessManager {
+		manager = extensions.ProcessManagerSupervisord
+	}
+	switch manager {
+	case "", "none":
+		data.Cmd = cmd
+		if len(cmd) > 0 {
+			data.CmdComment = "Container command"
+		} else {
+			data.Cmd = imageType.Command
+			data.CmdComment = imageType.CommandComment
+		}
+
+	case extensions.ProcessManagerSupervisord, extensions.ProcessManagerS6:
+		data.Manager = manager
+		if cfg.Command != "" {
+			data.Programs = append(data.Programs, &extensions.Program{Name: "app", Command: cfg.Command})
+		} else {
+			data.Programs = append(data.Programs, imageType.Programs...)
+		}
+		for _, program := range cfg.Programs {
+			for _, existing := range data.Programs {
+				if existing.Name == program.Name {
+					return nil, fmt.Errorf("PROCESS name '%s' is used by the main program", program.Name)
+				}
+			}
+			data.Programs = append(data.Programs, program)
+		}
+
+		if manager == extensions.ProcessManagerSupervisord {
+			data.Cmd = []string{"supervisord", "-c", SupervisordConfPath}
+			data.CmdComment = "Run the programs under supervisord"
+			data.SupervisordConf = supervisordConf(data.Programs)
+		} else {
+			data.Entrypoint = []string{"/init"}
+			data.S6Version = S6OverlayVersion
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported PROCESS_MANAGER: %s", manager)
+	}
+
+	if cfg.Init == extensions.InitTini {
+		base := data.Entrypoint
+		if len(base) == 0 {
+			base = []string{"docker-php-entrypoint"}
+		}
+		data.Entrypoint = append([]string{"tini", "--"}, base...)
+	}
+	return data, nil
+}
+
+// supervisordConf returns a supervisord configuration running programs in
+// the foreground with their output on the container's stdout and stderr
+func supervisordConf(programs []*extensions.Program) []string {
+	lines := []string{
+		"[supervisord]",
+		"nodaemon=true",
+		"user=root",
+		"logfile=/dev/null",
+		"logfile_maxbytes=0",
+		"pidfile=/run/supervisord.pid",
+	}
+	for _, program := range programs {
+		lines = append(lines,
+			"",
+			"[program:"+program.Name+"]",
+			// supervisord expands %(name)s in values
+			"command="+strings.ReplaceAll(program.Command, "%", "%%"),
+			"autorestart=true",
+			"stopasgroup=true",
+			"killasgroup=true",
+			"stdout_logfile=/dev/stdout",
+			"stdout_logfile_maxbytes=0",
+			"stderr_logfile=/dev/stderr",
+			"stderr_logfile_maxbytes=0",
+		)
+	}
+	return lines
+}
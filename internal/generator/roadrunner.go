@@ -0,0 +1,9 @@
+package generator
+
+// RoadRunnerVersion is the RoadRunner release installed in roadrunner images
+const RoadRunnerVersion = "2024.3.5"
+
+// RoadRunnerData holds the rr release downloaded into roadrunner images
+type RoadRunnerData struct {
+	Version string
+}
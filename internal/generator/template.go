@@ -3,8 +3,14 @@ package generator
 import (
 	"bytes"
 	"embed"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"text/template"
+	"text/template/parse"
 
 	"vess/internal/extensions"
 )
@@ -12,6 +18,35 @@ import (
 //go:embed templates/*.tmpl
 var templatesFS embed.FS
 
+// TemplateSchemaVersion is the version of the TemplateData contract seen by
+// templates. It is bumped whenever a field is removed or changes meaning.
+const TemplateSchemaVersion = 1
+
+// Blocks are the named templates that custom templates can override per OS
+// (e.g. alpine.header) or for every OS at once (e.g. header)
+var Blocks = []string{"header", "build-deps", "extensions", "final-stage", "entrypoint"}
+
+// templateOSes are the OS types with an embedded Dockerfile template
+var templateOSes = []string{"alpine", "ubuntu"}
+
+// funcMap holds the functions available to all templates
+var funcMap = template.FuncMap{
+	"minus1": func(n int) int { return n - 1 },
+	"join":   strings.Join,
+	"shellquote": func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	},
+	// execform renders words as the JSON array of an exec form instruction
+	"execform": func(words []string) string {
+		quoted := make([]string, len(words))
+		for i, word := range words {
+			data, _ := json.Marshal(word)
+			quoted[i] = string(data)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	},
+}
+
 // TemplateEngine wraps Go's template engine
 type TemplateEngine struct {
 	templates *template.Template
@@ -19,10 +54,6 @@ type TemplateEngine struct {
 
 // NewTemplateEngine creates a new template engine
 func NewTemplateEngine() (*TemplateEngine, error) {
-	funcMap := template.FuncMap{
-		"minus1": func(n int) int { return n - 1 },
-	}
-
 	tmpl, err := template.New("").Funcs(funcMap).ParseFS(templatesFS, "templates/*.tmpl")
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse templates: %w", err)
@@ -33,6 +64,52 @@ func NewTemplateEngine() (*TemplateEngine, error) {
 	}, nil
 }
 
+// LoadOverrides layers the *.tmpl files in dir over the embedded templates.
+// A file named like an embedded one (e.g. alpine.dockerfile.tmpl) replaces
+// it, and {{define}} blocks replace the embedded template of the same name.
+// A block from Blocks defined without an OS prefix applies to every OS
+// that does not define its own.
+func (te *TemplateEngine) LoadOverrides(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return fmt.Errorf("failed to list templates in %s: %w", dir, err)
+	}
+	if len(files) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("failed to read templates directory: %w", err)
+		}
+		return fmt.Errorf("no *.tmpl files found in %s", dir)
+	}
+
+	overrides, err := template.New("").Funcs(funcMap).ParseFiles(files...)
+	if err != nil {
+		return fmt.Errorf("failed to parse custom templates: %w", err)
+	}
+
+	for _, t := range overrides.Templates() {
+		// Files that only hold {{define}} blocks must not replace anything
+		if t.Tree == nil || parse.IsEmptyTree(t.Tree.Root) {
+			continue
+		}
+
+		names := []string{t.Name()}
+		if containsString(Blocks, t.Name()) {
+			names = nil
+			for _, osType := range templateOSes {
+				if overrides.Lookup(osType+"."+t.Name()) == nil {
+					names = append(names, osType+"."+t.Name())
+				}
+			}
+		}
+		for _, name := range names {
+			if _, err := te.templates.AddParseTree(name, t.Tree); err != nil {
+				return fmt.Errorf("failed to override template %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
 // Render renders a template with the given data
 func (te *TemplateEngine) Render(templateName string, data interface{}) (string, error) {
 	var buf bytes.Buffer
@@ -44,19 +121,222 @@ func (te *TemplateEngine) Render(templateName string, data interface{}) (string,
 	return buf.String(), nil
 }
 
-// TemplateData holds data for template rendering
+// TemplateData holds data for template rendering. Custom templates depend on
+// its fields; see TemplateSchemaVersion.
 type TemplateData struct {
+	SchemaVersion  int
 	PHPVersion     string
 	OSType         string
 	ImageType      string
 	BaseImage      string
+	BuilderImage   string
+	WorkDir        string
+	Port           string // exposed by the image type; empty for cli
+	PortComment    string
 	BuildDeps      []string
 	RuntimeDeps    []string
 	Extensions     []*ExtensionData
 	HasBuildDeps   bool
 	HasRuntimeDeps bool
+	Labels         []*Label
+	Optimized      bool
+	Install        *InstallPlan
+	// HasPackageManager is false for bases declared without apk or apt-get
+	HasPackageManager bool
+	// Extensions copied from the vess cache instead of being compiled
+	CachedExtensions   []string
+	CachedExtensionDir string
+	// IniFile holds the PHP_INI settings, if any
+	IniFile *IniFile
+	// Xdebug configures xdebug when it is a prod extension
+	Xdebug *XdebugData
+	// Hooks holds the HOOK_* instructions from the config
+	Hooks extensions.Hooks
+	// Healthcheck is rendered as HEALTHCHECK when enabled, or nil
+	Healthcheck *HealthcheckData
+	// Process holds the entrypoint, command and process manager programs
+	Process *ProcessData
+	// Nginx holds the server block of nginx-fpm images, or nil
+	Nginx *NginxData
+	// RoadRunner holds the rr release installed in roadrunner images, or nil
+	RoadRunner *RoadRunnerData
+	// Dev holds the dev-only part of the config; nil renders a single final stage
+	Dev *DevData
+
+	// systemBuildDeps are the SYSTEM_BUILD_PACKAGES, kept when BuildDeps is recomputed
+	systemBuildDeps []string
+}
+
+// DevData holds the dev target additions
+type DevData struct {
+	Extensions  []*ExtensionData
+	BuildDeps   []string
+	RuntimeDeps []string // runtime dependencies not already installed for prod
+	Install     *InstallPlan
+	IniFile     *IniFile
+	Xdebug      *XdebugData // set when xdebug is a dev extension
+	Labels      []*Label
+	ProdLabels  []*Label
+}
+
+// IniFile is a php.ini snippet written to conf.d
+type IniFile struct {
+	File     string
+	Settings []string
+}
+
+// XdebugData holds the xdebug mode and its conf.d snippet
+type XdebugData struct {
+	Mode    string
+	IniFile *IniFile
+}
+
+// newXdebugData returns the xdebug settings for cfg with defaults applied
+func newXdebugData(cfg extensions.XdebugConfig) *XdebugData {
+	cfg = cfg.WithDefaults()
+	return &XdebugData{
+		Mode: cfg.Mode,
+		IniFile: &IniFile{
+			File: "zz-xdebug.ini",
+			Settings: []string{
+				"xdebug.mode=" + cfg.Mode,
+				"xdebug.client_host=" + cfg.ClientHost,
+				"xdebug.client_port=" + cfg.ClientPort,
+				"xdebug.output_dir=/tmp",
+			},
+		},
+	}
+}
+
+// HealthcheckData holds the HEALTHCHECK options and probe command
+type HealthcheckData struct {
+	Interval    string
+	Timeout     string
+	StartPeriod string
+	Retries     string
+	Command     string
+	// PingPath enables the php-fpm ping endpoint probed by the default fpm health check
+	PingPath string
+}
+
+// FPMPingPath is the php-fpm ping.path used by the default fpm healthcheck
+const FPMPingPath = "/ping"
+
+// FrankenPHPAdminPort is the port of the Caddy admin API probed by the default frankenphp healthcheck
+const FrankenPHPAdminPort = "2019"
+
+// newHealthcheckData returns the healthcheck for imageType, using the probe
+// of the image type unless the config sets a command
+func newHealthcheckData(cfg extensions.HealthcheckConfig, imageType string) *HealthcheckData {
+	pathSet := cfg.Path != ""
+	cfg = cfg.WithDefaults()
+	data := &HealthcheckData{
+		Interval:    cfg.Interval,
+		Timeout:     cfg.Timeout,
+		StartPeriod: cfg.StartPeriod,
+		Retries:     cfg.Retries,
+		Command:     cfg.Command,
+	}
+	if data.Command != "" {
+		return data
+	}
+
+	switch imageType {
+	case "fpm":
+		data.PingPath = FPMPingPath
+		data.Command = fmt.Sprintf("SCRIPT_NAME=%s SCRIPT_FILENAME=%s REQUEST_METHOD=GET cgi-fcgi -bind -connect 127.0.0.1:9000 | grep -q pong || exit 1", FPMPingPath, FPMPingPath)
+	case "apache":
+		data.Command = fmt.Sprintf("curl -fsS -o /dev/null http://localhost%s || exit 1", cfg.Path)
+	case "frankenphp":
+		data.Command = fmt.Sprintf("curl -fsS -o /dev/null http://localhost:%s/config/ || exit 1", FrankenPHPAdminPort)
+	case "nginx-fpm":
+		// Probe php-fpm through nginx unless a path of the application is configured
+		if !pathSet {
+			data.PingPath = FPMPingPath
+			cfg.Path = FPMPingPath
+		}
+		data.Command = fmt.Sprintf("curl -fsS -o /dev/null http://localhost%s || exit 1", cfg.Path)
+	}
+	return data
+}
+
+// BuildStage describes a stage that compiles extensions
+type BuildStage struct {
+	Name         string
+	From         string
+	Optimized    bool
+	BuildDeps    []string
+	HasBuildDeps bool
+	RuntimeDeps  []string
+	Extensions   []*ExtensionData
+	Install      *InstallPlan
+	// Hook instructions rendered around the build dependencies and extensions
+	PreBuildDeps   []string
+	PostExtensions []string
 }
 
+// BuilderStage returns the stage compiling the prod extensions
+func (d *TemplateData) BuilderStage() *BuildStage {
+	return &BuildStage{
+		Name:           "builder",
+		From:           d.BuilderImage,
+		Optimized:      d.Optimized,
+		BuildDeps:      d.BuildDeps,
+		HasBuildDeps:   d.HasBuildDeps,
+		RuntimeDeps:    d.RuntimeDeps,
+		Extensions:     d.Extensions,
+		Install:        PlanInstall(d.Extensions),
+		PreBuildDeps:   d.Hooks.PreBuildDeps,
+		PostExtensions: d.Hooks.PostExtensions,
+	}
+}
+
+// HasPECL reports whether the stage installs PECL extensions, which need a compiler toolchain
+func (s *BuildStage) HasPECL() bool {
+	for _, ext := range s.Extensions {
+		if ext.PECLInstall {
+			return true
+		}
+	}
+	return false
+}
+
+// DevBuilderStage returns the stage compiling the dev extensions on top of the builder stage
+func (d *TemplateData) DevBuilderStage() *BuildStage {
+	if d.Dev == nil {
+		return nil
+	}
+	return &BuildStage{
+		Name:         "dev-builder",
+		From:         "builder",
+		Optimized:    d.Optimized,
+		BuildDeps:    d.Dev.BuildDeps,
+		HasBuildDeps: len(d.Dev.BuildDeps) > 0,
+		RuntimeDeps:  d.Dev.RuntimeDeps,
+		Extensions:   d.Dev.Extensions,
+		Install:      PlanInstall(d.Dev.Extensions),
+	}
+}
+
+// Label is an image label rendered into the final stage
+type Label struct {
+	Key   string
+	Value string
+}
+
+// Image labels identifying vess-built images
+const (
+	LabelManaged    = "io.vess.managed"
+	LabelConfig     = "io.vess.config"
+	LabelOS         = "io.vess.os"
+	LabelPHPVersion = "io.vess.php-version"
+	LabelImageType  = "io.vess.image-type"
+	LabelExtensions = "io.vess.extensions"
+	LabelDockerfile = "io.vess.dockerfile"
+	LabelTarget     = "io.vess.target"
+	LabelDevExts    = "io.vess.dev-extensions"
+)
+
 // ExtensionData holds extension-specific data for templates
 type ExtensionData struct {
 	Name        string
@@ -64,30 +344,282 @@ type ExtensionData struct {
 	PECLInstall bool
 }
 
+// UseCached removes the given extensions from the compiled set so that the
+// templates copy them from extensionDir instead
+func (d *TemplateData) UseCached(names []string, extensionDir string) {
+	if len(names) == 0 {
+		return
+	}
+
+	compiled := make([]*ExtensionData, 0, len(d.Extensions))
+	compiledNames := make([]string, 0, len(d.Extensions))
+	for _, ext := range d.Extensions {
+		if containsString(names, ext.Name) {
+			continue
+		}
+		compiled = append(compiled, ext)
+		compiledNames = append(compiledNames, ext.Name)
+	}
+
+	d.Extensions = compiled
+	d.BuildDeps = mergePackages(buildDeps(d.OSType, compiledNames), d.systemBuildDeps)
+	d.HasBuildDeps = len(d.BuildDeps) > 0
+	d.CachedExtensions = names
+	d.CachedExtensionDir = extensionDir
+}
+
+// buildDeps returns the build dependencies of extNames on osType
+func buildDeps(osType string, extNames []string) []string {
+	switch osType {
+	case "alpine":
+		return extensions.GetAlpineBuildDeps(extNames)
+	case "ubuntu":
+		return extensions.GetUbuntuBuildDeps(extNames)
+	default:
+		return nil
+	}
+}
+
+// runtimeDeps returns the runtime dependencies of extNames on osType
+func runtimeDeps(osType string, extNames []string) []string {
+	switch osType {
+	case "alpine":
+		return extensions.GetAlpineRuntimeDeps(extNames)
+	case "ubuntu":
+		return extensions.GetUbuntuRuntimeDeps(extNames)
+	default:
+		return nil
+	}
+}
+
+// mergePackages returns the packages of lists sorted and without duplicates
+func mergePackages(lists ...[]string) []string {
+	var merged []string
+	for _, list := range lists {
+		merged = appendUnique(merged, list...)
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// InstallPlan groups extension install commands so that the optimized
+// templates can run them in as few layers as possible
+type InstallPlan struct {
+	Configure []string // docker-php-ext-configure commands
+	Core      []string // extensions built by a single docker-php-ext-install
+	PECL      []string // extensions installed by a single pecl install
+	Enable    []string // extensions enabled by a single docker-php-ext-enable
+	Other     []string // commands that could not be merged, run as-is
+}
+
+// PlanInstall splits extension install commands into mergeable groups.
+// Commands containing anything other than docker-php-ext-configure,
+// docker-php-ext-install, pecl install and docker-php-ext-enable are kept whole.
+func PlanInstall(exts []*ExtensionData) *InstallPlan {
+	plan := &InstallPlan{}
+
+	for _, ext := range exts {
+		var configure, core, pecl, enable []string
+		mergeable := true
+
+		for _, part := range strings.Split(ext.InstallCmd, "&&") {
+			fields := strings.Fields(part)
+			switch {
+			case len(fields) > 1 && fields[0] == "docker-php-ext-configure":
+				configure = append(configure, strings.Join(fields, " "))
+			case len(fields) > 1 && fields[0] == "docker-php-ext-install" && !strings.HasPrefix(fields[1], "-"):
+				core = append(core, fields[1:]...)
+			case len(fields) > 2 && fields[0] == "pecl" && fields[1] == "install" && !strings.HasPrefix(fields[2], "-"):
+				pecl = append(pecl, fields[2:]...)
+			case len(fields) > 1 && fields[0] == "docker-php-ext-enable":
+				enable = append(enable, fields[1:]...)
+			default:
+				mergeable = false
+			}
+		}
+
+		if !mergeable {
+			plan.Other = append(plan.Other, ext.InstallCmd)
+			continue
+		}
+		plan.Configure = append(plan.Configure, configure...)
+		plan.Core = appendUnique(plan.Core, core...)
+		plan.PECL = appendUnique(plan.PECL, pecl...)
+		plan.Enable = appendUnique(plan.Enable, enable...)
+	}
+
+	return plan
+}
+
+// Commands returns the plan as a sequence of shell commands
+func (p *InstallPlan) Commands() []string {
+	commands := append([]string{}, p.Configure...)
+	if len(p.Core) > 0 {
+		commands = append(commands, "docker-php-ext-install -j$(nproc) "+strings.Join(p.Core, " "))
+	}
+	if len(p.PECL) > 0 {
+		commands = append(commands, "pecl install "+strings.Join(p.PECL, " "))
+	}
+	if len(p.Enable) > 0 {
+		commands = append(commands, "docker-php-ext-enable "+strings.Join(p.Enable, " "))
+	}
+	return append(commands, p.Other...)
+}
+
+// appendUnique appends values not already present in list
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		if !containsString(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// containsString checks if a slice contains a string
+func containsString(slice []string, str string) bool {
+	for _, item := range slice {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
+
 // PrepareTemplateData prepares data for template rendering
-func PrepareTemplateData(osType, phpVersion, imageType string, extNames []string) (*TemplateData, error) {
+func PrepareTemplateData(osType, phpVersion, imageType string, cfg *extensions.Config) (*TemplateData, error) {
+	extNames := cfg.Extensions
+	typ, ok := extensions.GetImageType(imageType)
+	if !ok {
+		return nil, fmt.Errorf("unsupported image type: %s", imageType)
+	}
 	data := &TemplateData{
-		PHPVersion: phpVersion,
-		OSType:     osType,
-		ImageType:  imageType,
-		Extensions: make([]*ExtensionData, 0, len(extNames)),
+		SchemaVersion: TemplateSchemaVersion,
+		PHPVersion:    phpVersion,
+		OSType:        osType,
+		ImageType:     imageType,
+		WorkDir:       typ.WorkDir,
+		Port:          typ.Port,
+		PortComment:   typ.PortComment,
+		Extensions:    make([]*ExtensionData, 0, len(extNames)),
 	}
+	data.BaseImage, data.BuilderImage = cfg.Base.Resolve(typ.BaseImage(osType, phpVersion), osType)
+	data.HasPackageManager = cfg.Base.HasPackageManager(osType)
 
-	// Set base image
-	if osType == "alpine" {
-		data.BaseImage = fmt.Sprintf("php:%s-%s-alpine", phpVersion, imageType)
-		data.BuildDeps = extensions.GetAlpineBuildDeps(extNames)
-		data.RuntimeDeps = extensions.GetAlpineRuntimeDeps(extNames)
-	} else if osType == "ubuntu" {
-		data.BaseImage = fmt.Sprintf("php:%s-%s", phpVersion, imageType)
-		data.BuildDeps = extensions.GetUbuntuBuildDeps(extNames)
-		data.RuntimeDeps = extensions.GetUbuntuRuntimeDeps(extNames)
+	data.systemBuildDeps = extensions.ResolvePackages(cfg.SystemBuildPackages, osType)
+	data.BuildDeps = mergePackages(buildDeps(osType, extNames), data.systemBuildDeps)
+	data.RuntimeDeps = mergePackages(runtimeDeps(osType, extNames), extensions.ResolvePackages(cfg.SystemPackages, osType), typ.Packages[osType])
+	process, err := newProcessData(cfg.Process, typ)
+	if err != nil {
+		return nil, err
+	}
+	data.Process = process
+	// The image type may imply a process manager the config does not name
+	processCfg := cfg.Process
+	processCfg.Manager = process.Manager
+	data.RuntimeDeps = mergePackages(data.RuntimeDeps, extensions.GetProcessPackages(processCfg, osType))
+	if cfg.Healthcheck.IsEnabled() {
+		data.Healthcheck = newHealthcheckData(cfg.Healthcheck, imageType)
+		if cfg.Healthcheck.Command == "" {
+			data.RuntimeDeps = mergePackages(data.RuntimeDeps, extensions.GetHealthcheckPackages(imageType, osType))
+		}
+	}
+	if imageType == "nginx-fpm" {
+		var pingPath string
+		if data.Healthcheck != nil {
+			pingPath = data.Healthcheck.PingPath
+		}
+		data.Nginx = newNginxData(cfg.Nginx, osType, pingPath)
+	}
+	if imageType == "roadrunner" {
+		data.RoadRunner = &RoadRunnerData{Version: RoadRunnerVersion}
 	}
 
 	data.HasBuildDeps = len(data.BuildDeps) > 0
 	data.HasRuntimeDeps = len(data.RuntimeDeps) > 0
 
 	// Prepare extension data
+	data.Extensions = extensionData(osType, extNames, cfg)
+	if len(cfg.Ini) > 0 {
+		data.IniFile = &IniFile{File: "zz-vess.ini", Settings: cfg.Ini}
+	}
+	if containsString(extNames, "xdebug") {
+		data.Xdebug = newXdebugData(cfg.Xdebug)
+	}
+
+	data.Hooks = cfg.Hooks
+
+	data.Labels = []*Label{
+		{Key: LabelManaged, Value: "true"},
+		{Key: LabelConfig, Value: cfg.Source},
+		{Key: LabelOS, Value: osType},
+		{Key: LabelPHPVersion, Value: phpVersion},
+		{Key: LabelImageType, Value: imageType},
+		{Key: LabelExtensions, Value: strings.Join(extNames, ",")},
+	}
+
+	if cfg.HasDevTarget() {
+		data.Dev = &DevData{
+			Extensions: extensionData(osType, cfg.DevExtensions, cfg),
+			BuildDeps:  mergePackages(buildDeps(osType, cfg.DevExtensions)),
+			Labels: []*Label{
+				{Key: LabelTarget, Value: extensions.TargetDev},
+				{Key: LabelDevExts, Value: strings.Join(cfg.DevExtensions, ",")},
+			},
+			ProdLabels: []*Label{
+				{Key: LabelTarget, Value: extensions.TargetProd},
+			},
+		}
+		for _, dep := range mergePackages(runtimeDeps(osType, cfg.DevExtensions)) {
+			if !containsString(data.RuntimeDeps, dep) {
+				data.Dev.RuntimeDeps = append(data.Dev.RuntimeDeps, dep)
+			}
+		}
+		if len(cfg.DevIni) > 0 {
+			data.Dev.IniFile = &IniFile{File: "zz-vess-dev.ini", Settings: cfg.DevIni}
+		}
+		if containsString(cfg.DevExtensions, "xdebug") {
+			data.Dev.Xdebug = newXdebugData(cfg.Xdebug)
+		}
+	}
+
+	if err := checkInstallers(data, cfg.Base); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// checkInstallers fails when a base image declared with BASE_INSTALLERS
+// cannot install what the image needs
+func checkInstallers(data *TemplateData, base extensions.BaseImageConfig) error {
+	manager := extensions.GetPackageManager(data.OSType)
+	if !data.HasPackageManager {
+		packages := data.RuntimeDeps
+		if data.Dev != nil {
+			packages = append(append([]string{}, packages...), data.Dev.RuntimeDeps...)
+		}
+		if len(packages) > 0 {
+			return fmt.Errorf("the base image has no %s (BASE_INSTALLERS) to install the runtime packages %s", manager, strings.Join(packages, ", "))
+		}
+	}
+
+	// The builder only uses the final base image when it has the PHP installers
+	if base.BuilderImage != "" || !base.HasInstaller(extensions.InstallerDockerPHPExt, data.OSType) {
+		return nil
+	}
+	builder := data.BuilderStage()
+	if !data.HasPackageManager && (builder.HasBuildDeps || builder.HasPECL()) {
+		return fmt.Errorf("the base image has no %s (BASE_INSTALLERS) to install build dependencies; set BUILDER_IMAGE", manager)
+	}
+	if builder.HasPECL() && !base.HasInstaller(extensions.InstallerPECL, data.OSType) {
+		return fmt.Errorf("the base image has no pecl (BASE_INSTALLERS) to install PECL extensions; set BUILDER_IMAGE")
+	}
+	return nil
+}
+
+// extensionData returns the install data of extNames on osType
+func extensionData(osType string, extNames []string, cfg *extensions.Config) []*ExtensionData {
+	result := make([]*ExtensionData, 0, len(extNames))
 	for _, extName := range extNames {
 		ext, exists := extensions.GetExtension(extName)
 		if !exists {
@@ -99,12 +631,16 @@ func PrepareTemplateData(osType, phpVersion, imageType string, extNames []string
 			continue
 		}
 
-		data.Extensions = append(data.Extensions, &ExtensionData{
+		installCmd := osSupport.InstallCmd
+		if version := cfg.PECLVersions[extName]; version != "" && osSupport.PECLInstall {
+			installCmd = strings.Replace(installCmd, "pecl install "+extName, "pecl install "+extName+"-"+version, 1)
+		}
+
+		result = append(result, &ExtensionData{
 			Name:        extName,
-			InstallCmd:  osSupport.InstallCmd,
+			InstallCmd:  installCmd,
 			PECLInstall: osSupport.PECLInstall,
 		})
 	}
-
-	return data, nil
+	return result
 }
@@ -0,0 +1,112 @@
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// FileName is the default name of the lock file
+const FileName = "vess.lock"
+
+// SchemaVersion is the version of the lock file format
+const SchemaVersion = 2
+
+// Lock records the resolved inputs of generated Dockerfiles: the digests of
+// the base images, the PECL extension and OS package versions, and the
+// vess and extension registry versions that rendered them
+type Lock struct {
+	Version int `json:"version"`
+	// Vess is the version of vess that wrote the lock
+	Vess string `json:"vess,omitempty"`
+	// Registry is the extension registry version, see extensions.RegistryVersion
+	Registry string `json:"registry,omitempty"`
+	// Images maps base image references to the digest they resolved to
+	Images map[string]string `json:"images"`
+	// PECL maps PHP versions to the resolved version of each PECL extension
+	PECL map[string]map[string]string `json:"pecl,omitempty"`
+	// Packages maps base image references to the versions of the OS
+	// packages installed on them
+	Packages map[string]map[string]string `json:"packages,omitempty"`
+}
+
+// New creates an empty lock
+func New() *Lock {
+	return &Lock{
+		Version:  SchemaVersion,
+		Images:   make(map[string]string),
+		PECL:     make(map[string]map[string]string),
+		Packages: make(map[string]map[string]string),
+	}
+}
+
+// Load reads the lock file at path; a missing file yields an empty lock
+func Load(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+
+	l := New()
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file %s: %w", path, err)
+	}
+	if l.Version > SchemaVersion {
+		return nil, fmt.Errorf("lock file %s has version %d; this vess supports up to %d", path, l.Version, SchemaVersion)
+	}
+	if l.Images == nil {
+		l.Images = make(map[string]string)
+	}
+	if l.PECL == nil {
+		l.PECL = make(map[string]map[string]string)
+	}
+	if l.Packages == nil {
+		l.Packages = make(map[string]map[string]string)
+	}
+	return l, nil
+}
+
+// Save writes the lock file to path
+func (l *Lock) Save(path string) error {
+	l.Version = SchemaVersion
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock file: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// PECLVersion returns the locked version of a PECL extension for phpVersion
+func (l *Lock) PECLVersion(phpVersion, extName string) (string, bool) {
+	version, ok := l.PECL[phpVersion][extName]
+	return version, ok
+}
+
+// SetPECLVersion records the version of a PECL extension for phpVersion
+func (l *Lock) SetPECLVersion(phpVersion, extName, version string) {
+	if l.PECL[phpVersion] == nil {
+		l.PECL[phpVersion] = make(map[string]string)
+	}
+	l.PECL[phpVersion][extName] = version
+}
+
+// PackageVersion returns the locked version of an OS package installed on image
+func (l *Lock) PackageVersion(image, pkg string) (string, bool) {
+	version, ok := l.Packages[image][pkg]
+	return version, ok
+}
+
+// SetPackageVersion records the version of an OS package installed on image
+func (l *Lock) SetPackageVersion(image, pkg, version string) {
+	if l.Packages[image] == nil {
+		l.Packages[image] = make(map[string]string)
+	}
+	l.Packages[image][pkg] = version
+}
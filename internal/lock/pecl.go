@@ -0,0 +1,111 @@
+package lock
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"vess/internal/extensions"
+)
+
+// PECLRestURL is the base URL of the PECL REST API
+const PECLRestURL = "https://pecl.php.net/rest/r"
+
+// peclHTTPClient is used for PECL REST API requests
+var peclHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// peclVersionPattern matches a PECL release version
+var peclVersionPattern = regexp.MustCompile(`^[0-9][0-9A-Za-z.]*$`)
+
+// peclReleases is the release list of a package (allreleases.xml)
+type peclReleases struct {
+	Releases []struct {
+		Version   string `xml:"v"`
+		Stability string `xml:"s"`
+	} `xml:"r"`
+}
+
+// peclPackage is the part of a release's package.xml vess reads
+type peclPackage struct {
+	PHP struct {
+		Min string `xml:"min"`
+		Max string `xml:"max"`
+	} `xml:"dependencies>required>php"`
+}
+
+// PECLVersion returns the newest stable release of a PECL package that
+// supports phpVersion according to its package.xml. Releases older than
+// extensions.MinPECLVersion are never returned.
+func PECLVersion(ctx context.Context, name, phpVersion string) (string, error) {
+	var releases peclReleases
+	if err := peclGet(ctx, name, "allreleases.xml", &releases); err != nil {
+		return "", err
+	}
+
+	var versions []string
+	for _, release := range releases.Releases {
+		if release.Stability == "stable" && peclVersionPattern.MatchString(release.Version) {
+			versions = append(versions, release.Version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return extensions.CompareVersions(versions[i], versions[j]) > 0
+	})
+
+	minVersion := extensions.MinPECLVersion(name, phpVersion)
+	for _, version := range versions {
+		if minVersion != "" && extensions.CompareVersions(version, minVersion) < 0 {
+			break
+		}
+
+		var pkg peclPackage
+		if err := peclGet(ctx, name, "package."+version+".xml", &pkg); err != nil {
+			return "", err
+		}
+		if supportsPHP(pkg, phpVersion) {
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("no stable release of %s supports PHP %s", name, phpVersion)
+}
+
+// supportsPHP checks phpVersion against the PHP requirement of a release.
+// Releases without a requirement support every version.
+func supportsPHP(pkg peclPackage, phpVersion string) bool {
+	if pkg.PHP.Min != "" && extensions.CompareVersions(phpVersion, pkg.PHP.Min) < 0 {
+		return false
+	}
+	if pkg.PHP.Max != "" && extensions.CompareVersions(phpVersion, pkg.PHP.Max) > 0 {
+		return false
+	}
+	return true
+}
+
+// peclGet fetches a file of a PECL package from the REST API and decodes it into v
+func peclGet(ctx context.Context, name, file string, v any) error {
+	url := fmt.Sprintf("%s/%s/%s", PECLRestURL, strings.ToLower(name), file)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create PECL request: %w", err)
+	}
+
+	resp, err := peclHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query PECL for %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("PECL returned %s for %s %s", resp.Status, name, file)
+	}
+
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse PECL %s for %s: %w", file, name, err)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"time"
 )
@@ -9,12 +10,14 @@ import (
 // Logger provides structured logging
 type Logger struct {
 	verbose bool
+	out     io.Writer
 }
 
 // New creates a new logger
 func New(verbose bool) *Logger {
 	return &Logger{
 		verbose: verbose,
+		out:     os.Stdout,
 	}
 }
 
@@ -71,10 +74,15 @@ func (l *Logger) log(level, format string, args ...interface{}) {
 	if level == "ERROR" {
 		fmt.Fprint(os.Stderr, output)
 	} else {
-		fmt.Fprint(os.Stdout, output)
+		fmt.Fprint(l.out, output)
 	}
 }
 
+// SetOutput sets the writer for non-error messages (stdout by default)
+func (l *Logger) SetOutput(w io.Writer) {
+	l.out = w
+}
+
 // SetVerbose sets the verbose flag
 func (l *Logger) SetVerbose(verbose bool) {
 	l.verbose = verbose
@@ -0,0 +1,109 @@
+package matrix
+
+import (
+	"fmt"
+	"time"
+
+	"vess/internal/config"
+	"vess/internal/extensions"
+)
+
+// Combination statuses
+const (
+	StatusSkipped   = "skipped"
+	StatusGenerated = "generated"
+	StatusPassed    = "passed"
+	StatusFailed    = "failed"
+)
+
+// Axes lists the values to combine
+type Axes struct {
+	OSTypes     []string
+	PHPVersions []string
+	ImageTypes  []string
+}
+
+// Combination is a single OS × PHP version × image type entry
+type Combination struct {
+	OSType     string        `json:"os"`
+	PHPVersion string        `json:"php_version"`
+	ImageType  string        `json:"image_type"`
+	Dockerfile string        `json:"dockerfile,omitempty"`
+	Tag        string        `json:"tag,omitempty"`
+	Status     string        `json:"status"`
+	Reason     string        `json:"reason,omitempty"`
+	ImageID    string        `json:"image_id,omitempty"`
+	Size       int64         `json:"size,omitempty"`
+	Duration   time.Duration `json:"duration,omitempty"`
+}
+
+// Name returns the combination name, matching the official php tag layout
+func (c *Combination) Name() string {
+	if c.OSType == "alpine" {
+		return fmt.Sprintf("%s-%s-alpine", c.PHPVersion, c.ImageType)
+	}
+	return fmt.Sprintf("%s-%s", c.PHPVersion, c.ImageType)
+}
+
+// Expand returns every combination of the axes. Combinations that are not
+// available, such as alpine with apache or an extension that does not support
+// a PHP version, are marked as skipped with the reason. Invalid axis values
+// and other config errors mark the combination as failed.
+func Expand(axes Axes, cfg *extensions.Config) []*Combination {
+	validator := config.NewValidator()
+	combinations := make([]*Combination, 0, len(axes.OSTypes)*len(axes.PHPVersions)*len(axes.ImageTypes))
+
+	for _, osType := range axes.OSTypes {
+		for _, phpVersion := range axes.PHPVersions {
+			for _, imageType := range axes.ImageTypes {
+				combination := &Combination{
+					OSType:     osType,
+					PHPVersion: phpVersion,
+					ImageType:  imageType,
+				}
+
+				if err := validator.CheckTarget(osType, phpVersion, imageType); err != nil {
+					combination.Status = StatusFailed
+					combination.Reason = err.Error()
+				} else if err := unavailable(validator, cfg, osType, phpVersion, imageType); err != nil {
+					combination.Status = StatusSkipped
+					combination.Reason = err.Error()
+				} else if err := validator.Validate(cfg, osType, phpVersion, imageType); err != nil {
+					combination.Status = StatusFailed
+					combination.Reason = err.Error()
+				}
+
+				combinations = append(combinations, combination)
+			}
+		}
+	}
+
+	return combinations
+}
+
+// unavailable reports why a combination of valid axis values cannot be
+// built: the image type is not published for the OS or PHP version, or a
+// known extension does not support them
+func unavailable(validator *config.Validator, cfg *extensions.Config, osType, phpVersion, imageType string) error {
+	if err := validator.CheckCompatibility(osType, phpVersion, imageType); err != nil {
+		return err
+	}
+	for _, extName := range cfg.TargetExtensions(extensions.TargetDev) {
+		if _, known := extensions.GetExtension(extName); !known {
+			continue
+		}
+		if err := validator.ValidateExtension(extName, osType, phpVersion); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Summary counts combinations by status
+func Summary(combinations []*Combination) map[string]int {
+	counts := make(map[string]int)
+	for _, c := range combinations {
+		counts[c.Status]++
+	}
+	return counts
+}
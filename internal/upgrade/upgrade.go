@@ -0,0 +1,200 @@
+package upgrade
+
+import (
+	"fmt"
+	"strings"
+
+	"vess/internal/config"
+	"vess/internal/diff"
+	"vess/internal/extensions"
+)
+
+// PECL actions
+const (
+	// ActionBump raises a pin older than the first release supporting the target
+	ActionBump = "bump"
+	// ActionKeep keeps a pin that supports the target
+	ActionKeep = "keep"
+	// ActionLatest notes an unpinned extension, for which pecl installs the latest release
+	ActionLatest = "latest"
+)
+
+// RemovedExtension is an extension the target PHP version does not support
+type RemovedExtension struct {
+	Name         string   `json:"name"`
+	Target       string   `json:"target"` // prod or dev
+	PHPVersions  []string `json:"php_versions"`
+	Alternatives []string `json:"alternatives,omitempty"`
+}
+
+// PECLChange is the version requirement of a PECL extension on the target
+type PECLChange struct {
+	Name     string `json:"name"`
+	Pinned   string `json:"pinned,omitempty"`
+	Required string `json:"required,omitempty"` // oldest release supporting the target
+	Action   string `json:"action"`
+}
+
+// Report is the migration report for moving a config to another PHP version
+type Report struct {
+	From    string              `json:"from"`
+	To      string              `json:"to"`
+	Removed []*RemovedExtension `json:"removed,omitempty"`
+	PECL    []*PECLChange       `json:"pecl,omitempty"`
+	// Changes compares the current config on From with the updated one on To
+	Changes *diff.Result `json:"changes"`
+	// Config is the updated config
+	Config *extensions.Config `json:"-"`
+}
+
+// Plan reports what moving cfg from PHP version from to to involves and
+// returns the updated config: extensions the target does not support are
+// removed and PECL pins older than the target's first supported release
+// are raised to it
+func Plan(cfg *extensions.Config, osType, imageType, from, to string) (*Report, error) {
+	// Every PHP version vess supports has cli images
+	validator := config.NewValidator()
+	for _, version := range []string{from, to} {
+		if err := validator.CheckCompatibility(osType, version, "cli"); err != nil {
+			return nil, err
+		}
+	}
+
+	updated := *cfg
+	updated.PECLVersions = make(map[string]string, len(cfg.PECLVersions))
+	for name, version := range cfg.PECLVersions {
+		updated.PECLVersions[name] = version
+	}
+	r := &Report{From: from, To: to, Config: &updated}
+
+	updated.Extensions = r.keepSupported(cfg.Extensions, extensions.TargetProd, to)
+	updated.DevExtensions = r.keepSupported(cfg.DevExtensions, extensions.TargetDev, to)
+	for _, removed := range r.Removed {
+		delete(updated.PECLVersions, removed.Name)
+	}
+
+	for _, extName := range updated.TargetExtensions(extensions.TargetDev) {
+		ext, _ := extensions.GetExtension(extName)
+		if ext == nil || ext.OSSupport[osType] == nil || !ext.OSSupport[osType].PECLInstall {
+			continue
+		}
+		change := &PECLChange{
+			Name:     extName,
+			Pinned:   updated.PECLVersions[extName],
+			Required: extensions.MinPECLVersion(extName, to),
+			Action:   ActionKeep,
+		}
+		switch {
+		case change.Pinned == "":
+			change.Action = ActionLatest
+		case change.Required != "" && extensions.CompareVersions(change.Pinned, change.Required) < 0:
+			change.Action = ActionBump
+			updated.PECLVersions[extName] = change.Required
+		}
+		r.PECL = append(r.PECL, change)
+	}
+
+	r.Changes = diff.Compare(
+		&diff.Target{Config: cfg, OSType: osType, PHPVersion: from, ImageType: imageType},
+		&diff.Target{Config: &updated, OSType: osType, PHPVersion: to, ImageType: imageType},
+	)
+	return r, nil
+}
+
+// keepSupported returns the extensions of target that support phpVersion,
+// recording the others as removed
+func (r *Report) keepSupported(extNames []string, target, phpVersion string) []string {
+	var kept []string
+	for _, extName := range extNames {
+		ext, ok := extensions.GetExtension(extName)
+		if !ok || extensions.SupportsVersion(extName, phpVersion) {
+			kept = append(kept, extName)
+			continue
+		}
+		r.Removed = append(r.Removed, &RemovedExtension{
+			Name:         extName,
+			Target:       target,
+			PHPVersions:  ext.PHPVersions,
+			Alternatives: extensions.GetAlternatives(extName),
+		})
+	}
+	return kept
+}
+
+// Bumped returns the PECL extensions whose pin is raised
+func (r *Report) Bumped() []*PECLChange {
+	var bumped []*PECLChange
+	for _, change := range r.PECL {
+		if change.Action == ActionBump {
+			bumped = append(bumped, change)
+		}
+	}
+	return bumped
+}
+
+// Rewrite applies the report to the content of the env file it was planned
+// for, keeping its comments and layout
+func (r *Report) Rewrite(content string) string {
+	removed := make(map[string]bool, len(r.Removed))
+	for _, ext := range r.Removed {
+		removed[ext.Name] = true
+	}
+
+	return config.RewriteEnv(content, func(key, value string) (string, bool) {
+		switch key {
+		case "PHP_EXTENSIONS", "PHP_DEV_EXTENSIONS":
+			var kept []string
+			for _, extName := range strings.Split(value, ",") {
+				if name := strings.TrimSpace(extName); name != "" && !removed[name] {
+					kept = append(kept, name)
+				}
+			}
+			if len(kept) == len(strings.Split(value, ",")) {
+				return value, true
+			}
+			return strings.Join(kept, ","), len(kept) > 0
+
+		case "PECL_VERSIONS":
+			var pairs []string
+			changed := false
+			for _, pair := range strings.Split(value, ",") {
+				name, version, _ := strings.Cut(pair, ":")
+				name, version = strings.TrimSpace(name), strings.TrimSpace(version)
+				if name == "" {
+					continue
+				}
+				if removed[name] {
+					changed = true
+					continue
+				}
+				if pinned := r.Config.PECLVersions[name]; pinned != "" && pinned != version {
+					version, changed = pinned, true
+				}
+				pairs = append(pairs, name+":"+version)
+			}
+			if !changed {
+				return value, true
+			}
+			return strings.Join(pairs, ","), len(pairs) > 0
+		}
+		return value, true
+	})
+}
+
+// Summary returns one line describing the size of the migration
+func (r *Report) Summary() string {
+	var parts []string
+	if len(r.Removed) > 0 {
+		parts = append(parts, fmt.Sprintf("%d extension(s) removed", len(r.Removed)))
+	}
+	if bumped := r.Bumped(); len(bumped) > 0 {
+		parts = append(parts, fmt.Sprintf("%d PECL pin(s) raised", len(bumped)))
+	}
+	if r.Changes.Issues != nil && len(r.Changes.Issues.Added) > 0 {
+		parts = append(parts, fmt.Sprintf("%d new issue(s)", len(r.Changes.Issues.Added)))
+	}
+	if len(parts) == 0 {
+		return "no changes to the config needed"
+	}
+	return strings.Join(parts, ", ")
+}